@@ -1,6 +1,6 @@
 // jsonconfig contains a set of useful structures for accessing JSON data from a
-// configuration file. It uses a pre-processor that removes //comments from the file
-// before parsing it.
+// configuration file. It uses a pre-processor that removes //comments and /* block comments */
+// from the file before parsing it.
 //
 //	package main
 //
@@ -48,8 +48,13 @@
 package jsonconfig
 
 import (
+	"bytes"
 	"encoding/json"
+	"io"
+	"math"
+	"math/big"
 	"os"
+	"reflect"
 	"strconv"
 	"strings"
 )
@@ -106,28 +111,89 @@ func (key JSONValue) String() string {
 	}
 }
 
-// Checks if the type of the JSON value is a float64 and if appropriate, casts it into an int.
+// Checks if the type of the JSON value is a float64 or json.Number and if appropriate, casts it into an int.
 func (key JSONValue) Integer() int {
 	switch typedValue := key.Value.(type) {
 	case float64:
 		return int(typedValue)
 	case int:
 		return typedValue
+	case json.Number:
+		return int(key.Int64())
 	default:
 		return 0
 	}
 }
 
-// Checks if the type of the JSON value is a float64 and if appropriate, casts it into a float64.
+// Checks if the type of the JSON value is a float64 or json.Number and if appropriate, casts it into a float64.
 func (key JSONValue) Number() float64 {
 	switch typedValue := key.Value.(type) {
 	case float64:
 		return typedValue
+	case json.Number:
+		number, _ := typedValue.Float64()
+		return number
 	default:
 		return 0
 	}
 }
 
+// Checks if the JSON value is a number and if appropriate, returns its exact value. Unlike Number,
+// no precision is lost when the configuration was loaded with PreciseNumbers. Returns nil if
+// the value isn't a number or is Infinity or NaN.
+func (key JSONValue) Decimal() *big.Rat {
+	switch typedValue := key.Value.(type) {
+	case float64:
+		if math.IsInf(typedValue, 0) || math.IsNaN(typedValue) {
+			return nil
+		}
+		return new(big.Rat).SetFloat64(typedValue)
+	case int:
+		return new(big.Rat).SetInt64(int64(typedValue))
+	case json.Number:
+		if decimal, ok := new(big.Rat).SetString(string(typedValue)); ok {
+			return decimal
+		}
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Returns the integer part of the JSON value without the loss of precision that comes from
+// going through a float64, provided the configuration was loaded with PreciseNumbers. Returns
+// 0 if the value isn't a number or doesn't fit in an int64.
+func (key JSONValue) Int64() int64 {
+	if typedValue, ok := key.Value.(json.Number); ok {
+		if integer, err := typedValue.Int64(); err == nil {
+			return integer
+		}
+	}
+	if integer := key.truncatedInteger(); integer != nil && integer.IsInt64() {
+		return integer.Int64()
+	}
+	return 0
+}
+
+// Returns the integer part of the JSON value without the loss of precision that comes from
+// going through a float64, provided the configuration was loaded with PreciseNumbers. Returns
+// 0 if the value isn't a number or doesn't fit in a uint64.
+func (key JSONValue) Uint64() uint64 {
+	if integer := key.truncatedInteger(); integer != nil && integer.IsUint64() {
+		return integer.Uint64()
+	}
+	return 0
+}
+
+// Returns the exact value of the number rounded towards zero, or nil if it isn't a number.
+func (key JSONValue) truncatedInteger() *big.Int {
+	decimal := key.Decimal()
+	if decimal == nil {
+		return nil
+	}
+	return new(big.Int).Quo(decimal.Num(), decimal.Denom())
+}
+
 // Checks if the type of the JSON value is a bool and if appropriate, casts it into a bool.
 func (key JSONValue) Boolean() bool {
 	switch typedValue := key.Value.(type) {
@@ -184,12 +250,89 @@ func (key JSONValue) collapse(path string, config Configuration) {
 //	}
 //
 // The value "used" will be returned by config["example.collision"].
+//
+// The values added are copies of the values they name, sharing their Arr and Obj, which is how
+// they are told apart from dotted keys in the file. Replacing a value after the configuration
+// has been collapsed leaves the copies as they were.
+//
+// Collapse adds keys to the objects within the configuration too, which gets in the way of ranging
+// over them. Index gives the same lookups without altering the configuration.
 func (config Configuration) Collapse() {
 	for childKey, childValue := range config {
 		childValue.collapse(childKey, config)
 	}
 }
 
+// Reports whether the member under key is a copy added by Collapse. Collapse copies values whole,
+// so a copy shares its Obj with the value it was copied from, which is found by following the
+// parts of the dotted key through the object. A key that was in the file holds a value of its own.
+// The copies don't see changes to the originals, so Merge and Assignments.Apply take them out
+// while the values change.
+func (config Configuration) addedByCollapse(key string) bool {
+	value, exists := config[key]
+	if !exists || value.Obj == nil || !strings.Contains(key, ".") {
+		return false
+	}
+
+	segments := strings.Split(key, ".")
+	for i := len(segments) - 1; i > 0; i-- {
+		if parent, ok := config[strings.Join(segments[:i], ".")]; ok && parent.holds(value, segments[i:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports whether the value at the path within key is target itself rather than a separate value,
+// however alike they are. Keys within the path may themselves contain dots.
+func (key JSONValue) holds(target JSONValue, segments []string) bool {
+	if len(segments) == 0 {
+		return reflect.ValueOf(key.Obj).Pointer() == reflect.ValueOf(target.Obj).Pointer()
+	}
+	for i := len(segments); i > 0; i-- {
+		if child, ok := key.Obj[strings.Join(segments[:i], ".")]; ok && child.holds(target, segments[i:]) {
+			return true
+		}
+	}
+	if index, err := strconv.Atoi(segments[0]); err == nil && index >= 0 && index < len(key.Arr) {
+		return key.Arr[index].holds(target, segments[1:])
+	}
+	return false
+}
+
+// Removes every key added by Collapse, from the configuration and from the objects within it.
+func (config Configuration) uncollapse() {
+	var added []string
+	for key, value := range config {
+		if config.addedByCollapse(key) {
+			added = append(added, key)
+		} else {
+			value.uncollapse()
+		}
+	}
+	for _, key := range added {
+		delete(config, key)
+	}
+}
+
+// Removes every key added by Collapse from the objects within the value.
+func (key JSONValue) uncollapse() {
+	key.Obj.uncollapse()
+	for _, element := range key.Arr {
+		element.uncollapse()
+	}
+}
+
+// Reports whether Collapse has added any keys to the configuration.
+func (config Configuration) collapsed() bool {
+	for key := range config {
+		if config.addedByCollapse(key) {
+			return true
+		}
+	}
+	return false
+}
+
 // Takes a "." delimited path and recursively uses the path, returning when a matching structure is found.
 // So this func will return used in the following example because example.collision gets matched before
 // example: { collision }.
@@ -207,33 +350,33 @@ func (config Configuration) Collapse() {
 //	  fmt.Println(key)
 //	}
 //
-// This func also can't handle arrays so for the following config
+// Arrays are followed by index, and a negative index counts back from the end, so for the config
 //
 //	{
 //	  "array": [
 //	    {
 //	      "value": 4
+//	    },
+//	    {
+//	      "value": 5
 //	    }
 //	  ]
 //	}
 //
-// config.Get("array.0.value").Num will return nothing while a collapsed config config["array.0.value"].Num
-// would return 4.
+// config.Get("array.0.value").Num returns 4 and config.Get("array.-1.value").Num returns 5, without
+// needing the config to be collapsed. A dot in a key can be escaped with a backslash, so
+// config.Get(`example\.collision`) only ever matches the key "example.collision", and a
+// backslash is escaped by doubling it. EscapeKey does this for you. A key written with escaped
+// dots is never joined with the keys around it, so config.Get(`a\.b.c`) is always "c" within
+// "a.b", even when there's a key "a.b.c".
 func (config Configuration) Get(path string) JSONValue {
 	if value, ok := config[path]; ok {
 		return value
-	} else {
-		keys := strings.Split(path, ".")
-		if len(keys) > 1 {
-			if subPath, subPathOk := config.Get(strings.Join(keys[0:len(keys)-1], ".")).Obj[keys[len(keys)-1]]; subPathOk {
-				return subPath
-			} else {
-				return NewJSONValue(nil)
-			}
-		} else {
-			return NewJSONValue(nil)
-		}
 	}
+	if value, _, ok := config.resolve(path); ok {
+		return value
+	}
+	return NewJSONValue(nil)
 }
 
 // Converts an abstract map of JSON data into a map of JSONValue.
@@ -245,27 +388,110 @@ func ConvertMap(from map[string]interface{}) Configuration {
 	return output
 }
 
-// Attempts to parse the file as a JSON object, removing any //comments in the process.
-func loadFileAsJSON(filename string) (Configuration, error) {
-	file, err := os.Open(filename)
+// Decodes a single value from the reader into target, which must be a pointer. Comments and
+// trailing commas are removed first, or if JSON5 was requested the value is parsed as JSON5.
+// Errors are returned as a *ParseError wherever the location of the problem is known.
+func decode(filename string, reader io.Reader, target interface{}, options *loadOptions) error {
+	source, err := io.ReadAll(reader)
 	if err != nil {
-		return Configuration{}, err
+		return err
 	}
 
-	untypedMap := map[string]interface{}{}
-	dec := json.NewDecoder(NewJsonCommentStripper(file))
-	if err = dec.Decode(&untypedMap); err != nil {
+	// With nothing to do to the abstract values they can go straight into the target
+	if !options.json5 && !options.transformsValues() {
+		processor := preProcess(bytes.NewReader(source), options)
+		decoder := json.NewDecoder(processor)
+		if options.preciseNumbers {
+			decoder.UseNumber()
+		}
+		if err = decoder.Decode(target); err != nil {
+			return locateError(filename, source, processor, err)
+		}
+		return nil
+	}
+
+	// Objects can be handed over as they are, which keeps values like NaN that can't be
+	// represented in plain JSON.
+	untypedMap, handOver := target.(*map[string]interface{})
+
+	// Anything else is encoded again and decoded into the target, so numbers are kept as
+	// json.Number to be encoded exactly as they were written.
+	parseOptions := options
+	if !handOver && !options.preciseNumbers {
+		preciseOptions := *options
+		preciseOptions.preciseNumbers = true
+		parseOptions = &preciseOptions
+	}
+
+	value, err := parseSource(filename, source, parseOptions)
+	if err != nil {
+		return err
+	}
+
+	if options.lookupEnv != nil {
+		if value, err = expandEnv("", value, options.lookupEnv); err != nil {
+			return err
+		}
+	}
+
+	if handOver {
+		if object, ok := value.(map[string]interface{}); ok {
+			*untypedMap = object
+			return nil
+		}
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(encoded, target); err != nil {
+		return locateTypeError(filename, source, options, err)
+	}
+	return nil
+}
+
+// Parses the source into abstract JSON values, as either JSON or JSON5.
+func parseSource(filename string, source []byte, options *loadOptions) (interface{}, error) {
+	if options.json5 {
+		value, err := parseJSON5(source, options.preciseNumbers, options.hashComments())
+		if err != nil {
+			return nil, locateError(filename, source, nil, err)
+		}
+		return value, nil
+	}
+
+	var value interface{}
+	processor := preProcess(bytes.NewReader(source), options)
+	decoder := json.NewDecoder(processor)
+	if options.preciseNumbers {
+		decoder.UseNumber()
+	}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, locateError(filename, source, processor, err)
+	}
+	return value, nil
+}
+
+// Attempts to parse the file as a JSON object, removing any comments and trailing commas in the process.
+// Any $include directives are resolved, and the absolute path of every file read is added to files.
+func loadFileAsJSON(filename string, options *loadOptions, files *[]string) (Configuration, error) {
+	var known interface{}
+	if options.strict {
+		known = options.known
+	}
+	untypedMap, err := loadIncludingFile(filename, options, nil, files, "", known)
+	if err != nil {
 		return Configuration{}, err
 	}
 
 	return ConvertMap(untypedMap), nil
 }
 
-// Attempts to parse the string as a JSON object, removing any //comments in the process.
-func loadStringAsJSON(jsonstr string) (Configuration, error) {
+// Attempts to parse the string as a JSON object, removing any comments and trailing commas in the process.
+func loadStringAsJSON(jsonstr string, options *loadOptions) (Configuration, error) {
 	untypedMap := map[string]interface{}{}
-	dec := json.NewDecoder(NewJsonCommentStripper(strings.NewReader(jsonstr)))
-	if err := dec.Decode(&untypedMap); err != nil {
+	if err := decode("", strings.NewReader(jsonstr), &untypedMap, options); err != nil {
 		return Configuration{}, err
 	}
 
@@ -277,39 +503,58 @@ func loadStringAsJSON(jsonstr string) (Configuration, error) {
 // is ignored unless the value in the other config and the calling config are both objects.
 // If the value is an object then the process is repeated, treating this key as a config in both
 // the calling config and other config.
+//
+// Use Merge for more control over how the configs are combined.
 func (config Configuration) MergeConfig(other Configuration) {
-	for key, value := range other {
-		if _, exists := config[key]; !exists {
-			config[key] = value
-		} else {
-			switch value.Value.(type) {
-			case map[string]interface{}:
-				switch config[key].Value.(type) {
-				case map[string]interface{}:
-					config[key].Obj.MergeConfig(other[key].Obj)
-				}
-			}
-		}
-	}
-	// config.Collapse()
+	config.Merge(other, MergeStrategy{})
 }
 
 // Loads the file containing a JSON object into an abstract map of JSONValue valueType.
 // You can provide a default configuration by providing a partial example of the config
 // file as a string. This call should be used over LoadAbstract if you wish to use range
 // on a JSON object. The collapse performed by LoadAbstract pollutes the keys of parent objects.
-func LoadAbstractNoCollapse(filename string, defaults string) (config Configuration, err error) {
-	config, err = loadFileAsJSON(filename)
-	if err != nil {
-		return
-	}
+// Use LoadIndexed for dotted path lookups as well.
+//
+// Any object in the file can pull in the contents of other files with an $include directive,
+// naming a single file or an array of files relative to the file containing the directive.
+//
+//	{
+//	  "database": {
+//	    "$include": "database.conf",
+//	    "port": 5433
+//	  },
+//	  "$include": ["logging.conf", "logging.local.conf"]
+//	}
+//
+// Members of the including object take precedence over included ones, and later files in a
+// list take precedence over earlier ones.
+func LoadAbstractNoCollapse(filename string, defaults string, options ...LoadOption) (config Configuration, err error) {
+	return loadAbstract(filename, defaults, newLoadOptions(options), nil)
+}
 
+// Does the work for LoadAbstractNoCollapse, adding the absolute path of every file read to files.
+func loadAbstract(filename string, defaults string, options *loadOptions, files *[]string) (config Configuration, err error) {
+	var defaultValues Configuration
 	if len(defaults) > 0 {
-		defaultValues, err := loadStringAsJSON(defaults)
-		if err != nil {
+		if defaultValues, err = loadStringAsJSON(defaults, options); err != nil {
 			return Configuration{}, err
 		}
-		config.MergeConfig(defaultValues)
+	}
+
+	if options.strict {
+		strictOptions := *options
+		strictOptions.known = defaultValues.raw()
+		options = &strictOptions
+	}
+
+	config, err = loadFileAsJSON(filename, options, files)
+	if err != nil {
+		return
+	}
+	config.MergeConfig(defaultValues)
+
+	if err = options.finishAbstract(config); err != nil {
+		return Configuration{}, err
 	}
 	return
 }
@@ -317,8 +562,8 @@ func LoadAbstractNoCollapse(filename string, defaults string) (config Configurat
 // Loads the file containing a JSON object into an abstract map of JSONValue valueType.
 // You can provide a default configuration by providing a partial example of the config
 // file as a string.
-func LoadAbstract(filename string, defaults string) (config Configuration, err error) {
-	config, err = LoadAbstractNoCollapse(filename, defaults)
+func LoadAbstract(filename string, defaults string, options ...LoadOption) (config Configuration, err error) {
+	config, err = LoadAbstractNoCollapse(filename, defaults, options...)
 	config.Collapse()
 	return
 }
@@ -326,37 +571,110 @@ func LoadAbstract(filename string, defaults string) (config Configuration, err e
 // Loads the JSON formatted string into an abstract map of JSONValue valueType.
 // You can provide a default configuration by providing a partial example of the config
 // file as a string.
-func LoadString(JSONString string, defaults string) (config Configuration, err error) {
+func LoadString(JSONString string, defaults string, options ...LoadOption) (config Configuration, err error) {
+	combinedOptions := newLoadOptions(options)
 	if len(JSONString) > 0 {
-		config, err = loadStringAsJSON(JSONString)
+		config, err = loadStringAsJSON(JSONString, combinedOptions)
 		if err != nil {
 			return Configuration{}, err
 		}
 	}
 
+	var defaultValues Configuration
 	if len(defaults) > 0 {
-		defaultValues, err := loadStringAsJSON(defaults)
-		if err != nil {
+		if defaultValues, err = loadStringAsJSON(defaults, combinedOptions); err != nil {
 			return Configuration{}, err
 		}
-		config.MergeConfig(defaultValues)
+	}
+
+	if combinedOptions.strict && len(JSONString) > 0 {
+		var paths []string
+		unknownKeys(config.raw(), defaultValues.raw(), "", &paths)
+		if err = errorsOrNil(locateUnknownKeys("", []byte(JSONString), combinedOptions, "", paths)); err != nil {
+			return Configuration{}, err
+		}
+	}
+	config.MergeConfig(defaultValues)
+
+	if err = combinedOptions.finishAbstract(config); err != nil {
+		return Configuration{}, err
 	}
 	return
 }
 
 // Loads the file containing a JSON object into the provided data structure. You can
 // provide default values by defining them in the provided data structure before handing
-// it to this func.
-func Load(filename string, config interface{}) error {
-	file, err := os.Open(filename)
+// it to this func, or with a default tag on the field.
+//
+//	type Server struct {
+//	  Host string        `json:"host" default:"localhost"`
+//	  TLS  *TLS          `json:"tls"`
+//	  Pool []Upstream    `json:"pool" default:"[{\"host\": \"a.internal\"}]"`
+//	  Wait time.Duration `json:"wait" default:"30s"`
+//	}
+//
+// A default is only used when the field isn't in the file at all, so an explicit zero, empty
+// string or null in the file is kept. Defaults are converted in the same way as EnvOverrides,
+// so structs, slices and maps are written as JSON. The fields of nested structs, of structs
+// in slices and maps, and of pointers to structs get their defaults too, including structs that
+// were themselves filled in from a default. A pointer that is missing from the file and has no
+// default of its own is left nil; give it a default of "{}" to have it filled in.
+//
+// Fields can be checked once they've been loaded with a jsonconfig tag listing rules
+// separated by commas.
+//
+//	type Server struct {
+//	  Host  string `json:"host" jsonconfig:"required"`
+//	  Port  int    `json:"port" jsonconfig:"min=1,max=65535,default=8080"`
+//	  Level string `json:"level" jsonconfig:"oneof=debug info warn error"`
+//	}
+//
+// The rules are
+//
+//	required    the field must be in the file, even if only as a zero value or null
+//	min=N       numbers must be at least N, and strings, slices and maps at least N long
+//	max=N       numbers must be at most N, and strings, slices and maps at most N long
+//	oneof=A B   the value must be one of the values separated by spaces
+//	default=V   the same as a default tag
+//
+// As a default may contain commas it must be the last rule. Fields that aren't in the file
+// and have no default are only checked against min, max and oneof if they were given a value
+// before loading. Every failure is reported together as ValidationErrors, with each field
+// named by its path in the file.
+func Load(filename string, config interface{}, options ...LoadOption) error {
+	source, err := os.ReadFile(filename)
 	if err != nil {
 		return err
 	}
 
-	dec := json.NewDecoder(NewJsonCommentStripper(file))
-	if err = dec.Decode(config); err != nil {
-		return err
+	combinedOptions := newLoadOptions(options)
+
+	// Schemas and jsonconfig tags need to see what was actually in the file
+	var tree map[string]interface{}
+	hasRules := hasFieldRules(reflect.TypeOf(config), map[reflect.Type]bool{})
+	if combinedOptions.schema != nil || combinedOptions.strict || hasRules {
+		if err = decode(filename, bytes.NewReader(source), &tree, combinedOptions); err != nil {
+			return err
+		}
+	}
+	if combinedOptions.strict {
+		var paths []string
+		unknownFields(tree, reflect.TypeOf(config), "", &paths)
+		if err = errorsOrNil(locateUnknownKeys(filename, source, combinedOptions, "", paths)); err != nil {
+			return err
+		}
+	}
+	if combinedOptions.schema != nil {
+		if err = combinedOptions.schema.Validate(ConvertMap(tree)); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	if err = decode(filename, bytes.NewReader(source), config, combinedOptions); err != nil {
+		return err
+	}
+	if !hasRules {
+		tree = nil
+	}
+	return combinedOptions.finishStruct(config, tree)
 }
@@ -0,0 +1,164 @@
+package jsonconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Describes how a path differs between two configurations.
+type ChangeKind int
+
+const (
+	Added ChangeKind = iota
+	Removed
+	Changed
+)
+
+func (kind ChangeKind) String() string {
+	switch kind {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	default:
+		return "changed"
+	}
+}
+
+// Writes the kind as its name, so that a diff rendered as JSON reads as "added" rather than 0.
+func (kind ChangeKind) MarshalText() ([]byte, error) {
+	return []byte(kind.String()), nil
+}
+
+// A single difference between two configurations. Old is nil for an added path and New is nil
+// for a removed one, so Kind tells those apart from a null value. Both are plain decoded JSON
+// values, as you'd get from encoding/json, and both are always rendered as JSON. Dots within the
+// keys of Path are escaped as by EscapeKey, so it can be passed straight to Get.
+type Change struct {
+	Path string      `json:"path"`
+	Kind ChangeKind  `json:"kind"`
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+}
+
+// Renders the change as a line such as
+//
+//	~ server.port: 80 -> 8080
+func (change Change) String() string {
+	switch change.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s: %s", change.Path, renderValue(change.New))
+	case Removed:
+		return fmt.Sprintf("- %s: %s", change.Path, renderValue(change.Old))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", change.Path, renderValue(change.Old), renderValue(change.New))
+	}
+}
+
+// Renders a value as compact JSON.
+func renderValue(value interface{}) string {
+	text, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(text)
+}
+
+// The differences between two configurations, ordered by path. It can be rendered for a person
+// with String, or as JSON with encoding/json.
+type Differences []Change
+
+// Renders the differences one per line, or "no changes" if there aren't any.
+func (differences Differences) String() string {
+	if len(differences) == 0 {
+		return "no changes"
+	}
+	lines := make([]string, len(differences))
+	for i, change := range differences {
+		lines[i] = change.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Lists the paths that were added, removed or changed going from a to b. Objects and arrays are
+// compared member by member, so a change deep inside an object is reported at its own dotted path
+// (an array element's path uses its index). A value that changes type, say from an object to a
+// string, is reported as a single change. Keys added by Collapse are ignored, so a collapsed
+// and an uncollapsed configuration compare the same.
+//
+//	for _, change := range jsonconfig.Diff(staging, production) {
+//	  fmt.Println(change)
+//	}
+func Diff(a Configuration, b Configuration) Differences {
+	differences := Differences{}
+	diffMembers(a, b, "", &differences)
+	return differences
+}
+
+// Compares the members of two objects.
+func diffMembers(a Configuration, b Configuration, path string, differences *Differences) {
+	keys := []string{}
+	for key := range a {
+		if !a.addedByCollapse(key) {
+			keys = append(keys, key)
+		}
+	}
+	for key := range b {
+		if _, inA := a[key]; !b.addedByCollapse(key) && (!inA || a.addedByCollapse(key)) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		old, inA := a[key]
+		new, inB := b[key]
+		inA = inA && !a.addedByCollapse(key)
+		inB = inB && !b.addedByCollapse(key)
+		switch {
+		case !inA:
+			*differences = append(*differences, Change{Path: joinPath(path, key), Kind: Added, New: new.raw()})
+		case !inB:
+			*differences = append(*differences, Change{Path: joinPath(path, key), Kind: Removed, Old: old.raw()})
+		default:
+			diffValues(old, new, joinPath(path, key), differences)
+		}
+	}
+}
+
+// Compares two values found at the same path.
+func diffValues(old JSONValue, new JSONValue, path string, differences *Differences) {
+	switch {
+	case old.Kind() == KindObject && new.Kind() == KindObject:
+		diffMembers(old.Obj, new.Obj, path, differences)
+	case old.Kind() == KindArray && new.Kind() == KindArray:
+		for i := 0; i < len(old.Arr) || i < len(new.Arr); i++ {
+			elementPath := joinPath(path, fmt.Sprint(i))
+			switch {
+			case i >= len(old.Arr):
+				*differences = append(*differences, Change{Path: elementPath, Kind: Added, New: new.Arr[i].raw()})
+			case i >= len(new.Arr):
+				*differences = append(*differences, Change{Path: elementPath, Kind: Removed, Old: old.Arr[i].raw()})
+			default:
+				diffValues(old.Arr[i], new.Arr[i], elementPath, differences)
+			}
+		}
+	case !sameValue(old, new):
+		*differences = append(*differences, Change{Path: path, Kind: Changed, Old: old.raw(), New: new.raw()})
+	}
+}
+
+// Checks if two values that aren't both objects or both arrays are the same. Numbers are compared
+// by value, so 1 loaded normally and 1 loaded with PreciseNumbers are the same.
+func sameValue(old JSONValue, new JSONValue) bool {
+	if old.Kind() == KindNumber && new.Kind() == KindNumber {
+		oldDecimal, newDecimal := old.Decimal(), new.Decimal()
+		if oldDecimal != nil && newDecimal != nil {
+			return oldDecimal.Cmp(newDecimal) == 0
+		}
+	}
+	return reflect.DeepEqual(old.raw(), new.raw())
+}
@@ -0,0 +1,110 @@
+package jsonconfig_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/callum-ramage/jsonconfig"
+)
+
+func TestDiff(test *testing.T) {
+	staging, _ := jsonconfig.LoadString(`{
+		"name": "service",
+		"server": {"host": "staging.internal", "port": 80},
+		"upstreams": ["a", "b"],
+		"debug": true
+	}`, "")
+	production, _ := jsonconfig.LoadString(`{
+		"name": "service",
+		"server": {"host": "prod.internal", "port": 80, "tls": {"enabled": true}},
+		"upstreams": ["a"],
+		"replicas": 3
+	}`, "")
+
+	expected := "- debug: true\n" +
+		"+ replicas: 3\n" +
+		"~ server.host: \"staging.internal\" -> \"prod.internal\"\n" +
+		"+ server.tls: {\"enabled\":true}\n" +
+		"- upstreams.1: \"b\""
+
+	differences := jsonconfig.Diff(staging, production)
+	if differences.String() != expected {
+		fmt.Println(differences)
+		test.Error()
+	}
+}
+
+func TestDiffDottedKeys(test *testing.T) {
+	old, _ := jsonconfig.LoadString(`{"hosts": {"api.internal": {"port": 80}}}`, "")
+	new, _ := jsonconfig.LoadString(`{"hosts": {"api.internal": {"port": 81}}}`, "")
+
+	differences := jsonconfig.Diff(old, new)
+	if len(differences) != 1 || differences[0].Path != `hosts.api\.internal.port` || new.Get(differences[0].Path).Value != float64(81) {
+		fmt.Println(differences)
+		test.Error()
+	}
+}
+
+func TestDiffJSON(test *testing.T) {
+	a, _ := jsonconfig.LoadString(`{"level": "info", "retries": 3}`, "")
+	b, _ := jsonconfig.LoadString(`{"level": {"default": "debug"}}`, "")
+
+	rendered, err := json.Marshal(jsonconfig.Diff(a, b))
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	expected := `[{"path":"level","kind":"changed","old":"info","new":{"default":"debug"}},{"path":"retries","kind":"removed","old":3,"new":null}]`
+	if string(rendered) != expected {
+		fmt.Println(string(rendered))
+		test.Error()
+	}
+
+	// Nulls are kept
+	a, _ = jsonconfig.LoadString(`{"x": null, "y": 1}`, "")
+	b, _ = jsonconfig.LoadString(`{"x": 1, "z": null}`, "")
+	rendered, _ = json.Marshal(jsonconfig.Diff(a, b))
+	expected = `[{"path":"x","kind":"changed","old":null,"new":1},{"path":"y","kind":"removed","old":1,"new":null},{"path":"z","kind":"added","old":null,"new":null}]`
+	if string(rendered) != expected {
+		fmt.Println(string(rendered))
+		test.Error()
+	}
+}
+
+func TestDiffIgnoresCollapse(test *testing.T) {
+	collapsed, _ := jsonconfig.LoadAbstract("./configs/ExampleComplexConfig.conf", "")
+	uncollapsed, _ := jsonconfig.LoadAbstractNoCollapse("./configs/ExampleComplexConfig.conf", "")
+
+	if len(collapsed) == len(uncollapsed) {
+		fmt.Println("the configuration wasn't collapsed")
+		test.Error()
+	}
+	if differences := jsonconfig.Diff(collapsed, uncollapsed); len(differences) != 0 || differences.String() != "no changes" {
+		fmt.Println(differences)
+		test.Error()
+	}
+	if differences := jsonconfig.Diff(uncollapsed, collapsed); len(differences) != 0 {
+		fmt.Println(differences)
+		test.Error()
+	}
+
+	// A real change is still found beneath the dotted keys
+	changed, _ := jsonconfig.LoadAbstract("./configs/ExampleComplexConfig.conf", `{"added": {"value": 1}}`)
+	if differences := jsonconfig.Diff(collapsed, changed); differences.String() != "+ added: {\"value\":1}" {
+		fmt.Println(differences)
+		test.Error()
+	}
+}
+
+func ExampleDiff() {
+	old, _ := jsonconfig.LoadString(`{"server": {"port": 80}}`, "")
+	new, _ := jsonconfig.LoadString(`{"server": {"port": 8080, "host": "0.0.0.0"}}`, "")
+
+	fmt.Println(jsonconfig.Diff(old, new))
+	// Output:
+	// + server.host: "0.0.0.0"
+	// ~ server.port: 80 -> 8080
+}
@@ -0,0 +1,591 @@
+package jsonconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A configuration file held as written, so that it can be edited by a program without losing the
+// comments, whitespace and key order a person gave it. Only the text of the values that are set
+// or deleted changes; everything else is written back byte for byte.
+//
+//	document, err := jsonconfig.LoadDocument("./configs/ExampleConfig.conf")
+//	if err != nil {
+//	  return
+//	}
+//	document.Set("example_object.example_number", 6)
+//	document.Delete("example_array.0")
+//	document.WriteFile("./configs/ExampleConfig.conf")
+//
+// Documents understand the same comments and trailing commas as LoadAbstract, but not JSON5.
+type Document struct {
+	filename     string
+	source       []byte
+	options      *loadOptions
+	hashComments bool
+	root         *cstNode
+}
+
+// A value within a Document, along with where it is in the text.
+type cstNode struct {
+	kind Kind
+	// The span of the value's text, from its first byte to just past its last
+	start int
+	end   int
+	// The members of an object or the elements of an array
+	entries []*cstEntry
+}
+
+// A member of an object or an element of an array.
+type cstEntry struct {
+	// The key of an object member, and the offset of its opening quote
+	key      string
+	keyStart int
+	value    *cstNode
+	// The offset of the comma following the entry, or -1 if there isn't one
+	comma int
+}
+
+// The offset of the first byte of the entry.
+func (e *cstEntry) start() int {
+	if e.keyStart >= 0 {
+		return e.keyStart
+	}
+	return e.value.start
+}
+
+// The offset just past the entry, including its comma.
+func (e *cstEntry) end() int {
+	if e.comma >= 0 {
+		return e.comma + 1
+	}
+	return e.value.end
+}
+
+// Loads a file as a Document. HashComments is the only LoadOption that has any effect, and
+// JSON5 is rejected.
+func LoadDocument(filename string, options ...LoadOption) (*Document, error) {
+	source, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return parseDocument(filename, source, newLoadOptions(options))
+}
+
+// Parses the text of a configuration as a Document. HashComments is the only LoadOption that
+// has any effect, and JSON5 is rejected.
+func ParseDocument(source []byte, options ...LoadOption) (*Document, error) {
+	return parseDocument("", source, newLoadOptions(options))
+}
+
+// Does the work for LoadDocument and ParseDocument.
+func parseDocument(filename string, source []byte, options *loadOptions) (*Document, error) {
+	if options.json5 {
+		return nil, errors.New("jsonconfig: JSON5 documents can't be edited")
+	}
+
+	parser := cstParser{source: source, hashComments: options.hashComments()}
+	root, err := parser.parse()
+	if err != nil {
+		return nil, locateError(filename, source, nil, err)
+	}
+	return &Document{filename: filename, source: source, options: options, hashComments: parser.hashComments, root: root}, nil
+}
+
+// Returns the text of the document, including any edits.
+func (d *Document) Bytes() []byte {
+	return append([]byte{}, d.source...)
+}
+
+// Writes the text of the document, including any edits, to the file.
+func (d *Document) WriteFile(filename string) error {
+	permissions := os.FileMode(0644)
+	if info, err := os.Stat(filename); err == nil {
+		permissions = info.Mode().Perm()
+	}
+	return os.WriteFile(filename, d.source, permissions)
+}
+
+// Returns the value at the dotted path, and whether there was one. As with Get, a key containing
+// dots is matched before it's split, and array elements are matched by index.
+func (d *Document) Value(path string) (JSONValue, bool) {
+	node, _, _, rest := d.root.find(splitPath(path))
+	if len(rest) > 0 {
+		return JSONValue{}, false
+	}
+
+	var value interface{}
+	if err := decode(d.filename, bytes.NewReader(d.source[node.start:node.end]), &value, d.options); err != nil {
+		return JSONValue{}, false
+	}
+	return NewJSONValue(value), true
+}
+
+// Sets the value at the dotted path, replacing the text of any value already there. Anything
+// that can be encoded by encoding/json can be set, along with a JSONValue or a Configuration.
+// Objects and arrays are indented to match the surrounding text.
+//
+// Missing members are added to the end of their object, with any missing objects along the path
+// created too. Setting the element one past the end of an array appends to it.
+func (d *Document) Set(path string, value interface{}) error {
+	switch typedValue := value.(type) {
+	case JSONValue:
+		value = typedValue.raw()
+	case Configuration:
+		value = typedValue.raw()
+	}
+
+	segments := splitPath(path)
+	node, parent, index, rest := d.root.find(segments)
+
+	if len(rest) == 0 {
+		indent := d.lineIndent(parent.entries[index].start())
+		text, err := d.render(value, indent)
+		if err != nil {
+			return err
+		}
+		return d.edit(edit{node.start, node.end, text})
+	}
+
+	switch node.kind {
+	case KindObject:
+		// Build the missing objects from the inside out
+		for i := len(rest) - 1; i > 0; i-- {
+			value = map[string]interface{}{rest[i]: value}
+		}
+		return d.insert(node, rest[0], value)
+	case KindArray:
+		if index, err := strconv.Atoi(rest[0]); err == nil && index == len(node.entries) && len(rest) == 1 {
+			return d.insert(node, "", value)
+		}
+	}
+	return fmt.Errorf("jsonconfig: can't set %s: %w", path, ErrUnknownPath)
+}
+
+// Deletes the member or element at the dotted path. When the entry is on lines of its own those
+// lines are removed along with any comment following it on its last line.
+func (d *Document) Delete(path string) error {
+	_, parent, index, rest := d.root.find(splitPath(path))
+	if len(rest) > 0 || parent == nil {
+		return fmt.Errorf("jsonconfig: can't delete %s: %w", path, ErrUnknownPath)
+	}
+
+	entry := parent.entries[index]
+	var previous, next *cstEntry
+	if index > 0 {
+		previous = parent.entries[index-1]
+	}
+	if index+1 < len(parent.entries) {
+		next = parent.entries[index+1]
+	}
+
+	lineStart := d.lineStart(entry.start())
+	if d.onlyWhitespace(lineStart, entry.start()) {
+		// Take the whole of the entry's lines
+		end := entry.end()
+		if lineEnd := d.lineEnd(end); d.onlyTrivia(end, lineEnd) && lineEnd < len(d.source) {
+			end = lineEnd + 1
+			edits := []edit{{lineStart, end, ""}}
+			// The entry before the last one can't be left with a comma unless the file already
+			// uses trailing commas
+			if next == nil && previous != nil && previous.comma >= 0 && entry.comma < 0 {
+				edits = append(edits, edit{previous.comma, previous.comma + 1, ""})
+			}
+			return d.edit(edits...)
+		}
+	}
+
+	switch {
+	case next != nil:
+		return d.edit(edit{entry.start(), next.start(), ""})
+	case previous != nil && previous.comma >= 0:
+		return d.edit(edit{previous.comma, entry.end(), ""})
+	default:
+		return d.edit(edit{entry.start(), entry.end(), ""})
+	}
+}
+
+// Adds a member with the key to an object, or an element to an array when key is empty.
+func (d *Document) insert(container *cstNode, key string, value interface{}) error {
+	render := func(indent string) (string, error) {
+		text, err := d.render(value, indent)
+		if err != nil || container.kind == KindArray {
+			return text, err
+		}
+		renderedKey, err := d.render(key, "")
+		return renderedKey + ": " + text, err
+	}
+
+	if len(container.entries) == 0 {
+		closing := container.end - 1
+		if !bytes.Contains(d.source[container.start:closing], []byte("\n")) {
+			text, err := render(d.lineIndent(container.start))
+			if err != nil {
+				return err
+			}
+			return d.edit(edit{container.start + 1, container.start + 1, text})
+		}
+
+		indent := d.lineIndent(closing) + d.indentUnit()
+		text, err := render(indent)
+		if err != nil {
+			return err
+		}
+		return d.edit(edit{container.start + 1, container.start + 1, "\n" + indent + text})
+	}
+
+	last := container.entries[len(container.entries)-1]
+	lastLineStart := d.lineStart(last.start())
+	lineEnd := d.lineEnd(last.end())
+	ownLine := d.onlyWhitespace(lastLineStart, last.start()) && d.onlyTrivia(last.end(), lineEnd) && lineEnd < container.end
+
+	indent := d.lineIndent(last.start())
+	entryText, err := render(indent)
+	if err != nil {
+		return err
+	}
+
+	if !ownLine {
+		// Everything is on one line, so the new entry follows on the same line
+		if last.comma >= 0 {
+			return d.edit(edit{last.end(), last.end(), " " + entryText + ","})
+		}
+		return d.edit(edit{last.end(), last.end(), ", " + entryText})
+	}
+
+	// Put the entry on a line of its own after the last one, keeping any comment after the last
+	// entry where it is
+	if last.comma >= 0 {
+		return d.edit(edit{lineEnd, lineEnd, "\n" + indent + entryText + ","})
+	}
+	return d.edit(
+		edit{lineEnd, lineEnd, "\n" + indent + entryText},
+		edit{last.value.end, last.value.end, ","},
+	)
+}
+
+// Encodes a value as JSON, with objects and arrays spread over lines indented from indent.
+func (d *Document) render(value interface{}, indent string) (string, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent(indent, d.indentUnit())
+	if err := encoder.Encode(value); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buffer.String(), "\n"), nil
+}
+
+// Works out how far the document indents each level, from the first entry on a line of its own.
+// Two spaces are used if nothing is indented.
+func (d *Document) indentUnit() string {
+	var search func(node *cstNode) string
+	search = func(node *cstNode) string {
+		for _, entry := range node.entries {
+			if d.onlyWhitespace(d.lineStart(entry.start()), entry.start()) {
+				entryIndent := d.lineIndent(entry.start())
+				containerIndent := d.lineIndent(node.start)
+				if len(entryIndent) > len(containerIndent) && strings.HasPrefix(entryIndent, containerIndent) {
+					return entryIndent[len(containerIndent):]
+				}
+			}
+			if unit := search(entry.value); unit != "" {
+				return unit
+			}
+		}
+		return ""
+	}
+
+	if unit := search(d.root); unit != "" {
+		return unit
+	}
+	return "  "
+}
+
+// Returns the offset of the start of the line containing offset.
+func (d *Document) lineStart(offset int) int {
+	return bytes.LastIndexByte(d.source[:offset], '\n') + 1
+}
+
+// Returns the offset of the newline ending the line containing offset, or the end of the source.
+func (d *Document) lineEnd(offset int) int {
+	if end := bytes.IndexByte(d.source[offset:], '\n'); end >= 0 {
+		return offset + end
+	}
+	return len(d.source)
+}
+
+// Returns the whitespace at the start of the line containing offset.
+func (d *Document) lineIndent(offset int) string {
+	start := d.lineStart(offset)
+	end := start
+	for end < len(d.source) && (d.source[end] == ' ' || d.source[end] == '\t') {
+		end++
+	}
+	return string(d.source[start:end])
+}
+
+// Checks if there's nothing but spaces and tabs between the offsets.
+func (d *Document) onlyWhitespace(start int, end int) bool {
+	return len(bytes.Trim(d.source[start:end], " \t")) == 0
+}
+
+// Checks if there's nothing but whitespace and comments between the offsets.
+func (d *Document) onlyTrivia(start int, end int) bool {
+	parser := cstParser{source: d.source[:end], pos: start, hashComments: d.hashComments}
+	return parser.skipTrivia() == nil && parser.pos == end
+}
+
+// A replacement of the source between two offsets.
+type edit struct {
+	start int
+	end   int
+	text  string
+}
+
+// Applies the edits to the source and parses it again. The edits mustn't overlap, and where two
+// insert at the same offset the text of the later one comes first.
+func (d *Document) edit(edits ...edit) error {
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+
+	source := append([]byte{}, d.source...)
+	for _, e := range edits {
+		source = append(source[:e.start], append([]byte(e.text), source[e.end:]...)...)
+	}
+
+	parser := cstParser{source: source, hashComments: d.hashComments}
+	root, err := parser.parse()
+	if err != nil {
+		return fmt.Errorf("jsonconfig: edit produced an invalid document: %w", err)
+	}
+	d.source = source
+	d.root = root
+	return nil
+}
+
+// Follows the path as far as it goes from the node, returning the last value reached, the
+// container holding it and its index there, and whatever is left of the path. As with resolve,
+// a key containing dots is matched before it's split.
+func (node *cstNode) find(segments []string) (found *cstNode, parent *cstNode, index int, rest []string) {
+	found, rest = node, segments
+	for len(rest) > 0 {
+		next := -1
+		consumed := 0
+		switch found.kind {
+		case KindObject:
+			for i := len(rest); i > 0 && next < 0; i-- {
+				key, ok := joinSegments(rest, i)
+				if !ok {
+					continue
+				}
+				// Later duplicates win, as they do when decoding
+				for j := len(found.entries) - 1; j >= 0; j-- {
+					if found.entries[j].key == key {
+						next, consumed = j, i
+						break
+					}
+				}
+			}
+		case KindArray:
+			if position, ok := arrayIndex(rest[0], len(found.entries)); ok {
+				next, consumed = position, 1
+			}
+		}
+		if next < 0 {
+			return
+		}
+		parent, index = found, next
+		found = found.entries[next].value
+		rest = rest[consumed:]
+	}
+	return
+}
+
+// Parses the text of a Document into cstNodes.
+type cstParser struct {
+	source       []byte
+	pos          int
+	hashComments bool
+	// How many arrays and objects enclose the current position
+	depth int
+}
+
+// Parses a whole document, which must hold a single value.
+func (p *cstParser) parse() (*cstNode, error) {
+	if err := p.skipTrivia(); err != nil {
+		return nil, err
+	}
+	node, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.skipTrivia(); err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.source) {
+		return nil, p.errorf("invalid character %q after top-level value", p.source[p.pos])
+	}
+	return node, nil
+}
+
+// Builds a syntax error for the current position.
+func (p *cstParser) errorf(format string, args ...interface{}) error {
+	return &json5SyntaxError{message: fmt.Sprintf(format, args...), Offset: int64(p.pos)}
+}
+
+// Skips whitespace and comments.
+func (p *cstParser) skipTrivia() error {
+	for p.pos < len(p.source) {
+		switch character := p.source[p.pos]; {
+		case character == ' ' || character == '\t' || character == '\n' || character == '\r':
+			p.pos++
+		case character == '#' && p.hashComments, bytes.HasPrefix(p.source[p.pos:], []byte("//")):
+			if end := bytes.IndexByte(p.source[p.pos:], '\n'); end >= 0 {
+				p.pos += end
+			} else {
+				p.pos = len(p.source)
+			}
+		case bytes.HasPrefix(p.source[p.pos:], []byte("/*")):
+			end := bytes.Index(p.source[p.pos+2:], []byte("*/"))
+			if end < 0 {
+				p.pos = len(p.source)
+				return ErrUnterminatedComment
+			}
+			p.pos += end + 4
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// Parses any value, leaving the position just past it.
+func (p *cstParser) parseValue() (*cstNode, error) {
+	if p.pos == len(p.source) {
+		return nil, p.errorf("unexpected end of input")
+	}
+
+	start := p.pos
+	switch character := p.source[p.pos]; {
+	case character == '{':
+		return p.parseContainer(KindObject, '}')
+	case character == '[':
+		return p.parseContainer(KindArray, ']')
+	case character == '"':
+		if _, err := p.parseString(); err != nil {
+			return nil, err
+		}
+		return &cstNode{kind: KindString, start: start, end: p.pos}, nil
+	}
+
+	// Numbers and literals run until the next separator
+	for p.pos < len(p.source) && !bytes.ContainsAny(p.source[p.pos:p.pos+1], " \t\r\n,:]}/#[{\"") {
+		p.pos++
+	}
+	text := p.source[start:p.pos]
+	switch {
+	case string(text) == "true" || string(text) == "false":
+		return &cstNode{kind: KindBool, start: start, end: p.pos}, nil
+	case string(text) == "null":
+		return &cstNode{kind: KindNull, start: start, end: p.pos}, nil
+	case len(text) > 0 && json.Valid(text):
+		return &cstNode{kind: KindNumber, start: start, end: p.pos}, nil
+	}
+	p.pos = start
+	return nil, p.errorf("invalid character %q looking for beginning of value", p.source[p.pos])
+}
+
+// Parses an object or an array, allowing a trailing comma.
+func (p *cstParser) parseContainer(kind Kind, closing byte) (*cstNode, error) {
+	if p.depth >= maxNestingDepth {
+		return nil, p.errorf("exceeded max depth")
+	}
+	p.depth++
+	defer func() { p.depth-- }()
+
+	node := &cstNode{kind: kind, start: p.pos}
+	p.pos++
+
+	for {
+		if err := p.skipTrivia(); err != nil {
+			return nil, err
+		}
+		if p.pos < len(p.source) && p.source[p.pos] == closing {
+			p.pos++
+			node.end = p.pos
+			return node, nil
+		}
+
+		entry := &cstEntry{keyStart: -1, comma: -1}
+		if kind == KindObject {
+			if p.pos == len(p.source) || p.source[p.pos] != '"' {
+				return nil, p.errorf("invalid character looking for beginning of object key string")
+			}
+			entry.keyStart = p.pos
+			key, err := p.parseString()
+			if err != nil {
+				return nil, err
+			}
+			entry.key = key
+
+			if err = p.skipTrivia(); err != nil {
+				return nil, err
+			}
+			if p.pos == len(p.source) || p.source[p.pos] != ':' {
+				return nil, p.errorf("invalid character after object key")
+			}
+			p.pos++
+			if err = p.skipTrivia(); err != nil {
+				return nil, err
+			}
+		}
+
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		entry.value = value
+		node.entries = append(node.entries, entry)
+
+		if err = p.skipTrivia(); err != nil {
+			return nil, err
+		}
+		switch {
+		case p.pos < len(p.source) && p.source[p.pos] == ',':
+			entry.comma = p.pos
+			p.pos++
+		case p.pos < len(p.source) && p.source[p.pos] == closing:
+		default:
+			return nil, p.errorf("invalid character after %s element", kind)
+		}
+	}
+}
+
+// Parses a string, returning its decoded value.
+func (p *cstParser) parseString() (string, error) {
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.source) {
+		switch p.source[p.pos] {
+		case '\\':
+			p.pos += 2
+			continue
+		case '"':
+			p.pos++
+			var value string
+			if err := json.Unmarshal(p.source[start:p.pos], &value); err != nil {
+				p.pos = start
+				return "", p.errorf("invalid string")
+			}
+			return value, nil
+		}
+		p.pos++
+	}
+	return "", p.errorf("unexpected end of input within string")
+}
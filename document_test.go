@@ -0,0 +1,191 @@
+package jsonconfig_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/callum-ramage/jsonconfig"
+)
+
+// Loads the example document, failing the test if it can't.
+func loadServiceDocument(test *testing.T) *jsonconfig.Document {
+	document, err := jsonconfig.LoadDocument("./configs/document/Service.conf")
+	if err != nil {
+		test.Fatal(err)
+	}
+	return document
+}
+
+func TestDocumentUnchanged(test *testing.T) {
+	source, _ := os.ReadFile("./configs/document/Service.conf")
+	document := loadServiceDocument(test)
+	if string(document.Bytes()) != string(source) {
+		fmt.Println(string(document.Bytes()))
+		test.Error()
+	}
+
+	if value, ok := document.Value("server.port"); !ok || value.Int != 8080 {
+		fmt.Println(value, ok)
+		test.Error()
+	}
+	if value, ok := document.Value("upstreams.1"); !ok || value.Str != "b.internal" {
+		fmt.Println(value, ok)
+		test.Error()
+	}
+	if _, ok := document.Value("server.missing"); ok {
+		test.Error()
+	}
+
+	// Escaped dots work as they do with Get
+	document, _ = jsonconfig.ParseDocument([]byte(`{"a.b.c": 1, "a.b": {"c": 2}}`))
+	if value, ok := document.Value(`a\.b.c`); !ok || value.Int != 2 {
+		fmt.Println(value, ok)
+		test.Error()
+	}
+}
+
+func TestDocumentSet(test *testing.T) {
+	document := loadServiceDocument(test)
+
+	edits := map[string]interface{}{
+		"server.port":     9090,
+		"name":            "invoicing",
+		"server.tls.cert": "/etc/cert.pem",
+		"upstreams.2":     "c.internal",
+		"limits.timeout":  "5s",
+		"features.beta":   []string{"search"},
+	}
+	for _, path := range []string{"server.port", "name", "server.tls.cert", "upstreams.2", "limits.timeout", "features.beta"} {
+		if err := document.Set(path, edits[path]); err != nil {
+			fmt.Println(path, err)
+			test.Error()
+		}
+	}
+
+	expected := `// Settings for the billing service
+{
+  "name": "invoicing", // Shown in dashboards
+  "server": {
+    /* Where to listen */
+    "host": "0.0.0.0",
+    "port": 9090,
+    "tls": {
+      "cert": "/etc/cert.pem"
+    }
+  },
+  "upstreams": [
+    "a.internal",
+    "b.internal", // Being retired
+    "c.internal",
+  ],
+  "limits": {"requests": 100, "burst": 10, "timeout": "5s"},
+  "features": {"beta": [
+    "search"
+  ]}
+}
+`
+	if string(document.Bytes()) != expected {
+		fmt.Println(string(document.Bytes()))
+		test.Error()
+	}
+
+	// The result still loads
+	config, err := jsonconfig.LoadString(string(document.Bytes()), "")
+	if err != nil || config.Get("server.tls.cert").Str != "/etc/cert.pem" {
+		fmt.Println(err)
+		test.Error()
+	}
+}
+
+func TestDocumentSetErrors(test *testing.T) {
+	document := loadServiceDocument(test)
+
+	for _, path := range []string{"name.first", "upstreams.5", "upstreams.x"} {
+		if err := document.Set(path, 1); !errors.Is(err, jsonconfig.ErrUnknownPath) {
+			fmt.Println(path, err)
+			test.Error()
+		}
+	}
+	if err := document.Set("name", func() {}); err == nil {
+		test.Error()
+	}
+}
+
+func TestDocumentDelete(test *testing.T) {
+	document := loadServiceDocument(test)
+
+	for _, path := range []string{"server.port", "upstreams.1", "limits.requests", "name"} {
+		if err := document.Delete(path); err != nil {
+			fmt.Println(path, err)
+			test.Error()
+		}
+	}
+	if err := document.Delete("missing"); !errors.Is(err, jsonconfig.ErrUnknownPath) {
+		fmt.Println(err)
+		test.Error()
+	}
+
+	expected := `// Settings for the billing service
+{
+  "server": {
+    /* Where to listen */
+    "host": "0.0.0.0"
+  },
+  "upstreams": [
+    "a.internal",
+  ],
+  "limits": {"burst": 10},
+  "features": {}
+}
+`
+	if string(document.Bytes()) != expected {
+		fmt.Println(string(document.Bytes()))
+		test.Error()
+	}
+}
+
+func TestDocumentWriteFile(test *testing.T) {
+	document, err := jsonconfig.ParseDocument([]byte("# Counter\n{\"count\": 1}\n"), jsonconfig.HashComments())
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+	document.Set("count", 2)
+
+	filename := filepath.Join(test.TempDir(), "Counter.conf")
+	if err = document.WriteFile(filename); err != nil {
+		fmt.Println(err)
+		test.Error()
+	}
+	written, _ := os.ReadFile(filename)
+	if string(written) != "# Counter\n{\"count\": 2}\n" {
+		fmt.Println(string(written))
+		test.Error()
+	}
+}
+
+func TestParseDocumentErrors(test *testing.T) {
+	var parseError *jsonconfig.ParseError
+	if _, err := jsonconfig.ParseDocument([]byte("{\n  \"a\": tru\n}")); !errors.As(err, &parseError) || parseError.Line != 2 {
+		fmt.Println(err)
+		test.Error()
+	}
+	if _, err := jsonconfig.ParseDocument([]byte("{} /* open")); !errors.Is(err, jsonconfig.ErrUnterminatedComment) {
+		fmt.Println(err)
+		test.Error()
+	}
+	if _, err := jsonconfig.ParseDocument([]byte("{}"), jsonconfig.JSON5()); err == nil {
+		test.Error()
+	}
+
+	deep := `{"a": ` + strings.Repeat("[", 100000) + strings.Repeat("]", 100000) + "}"
+	if _, err := jsonconfig.ParseDocument([]byte(deep)); !errors.As(err, &parseError) || !strings.Contains(err.Error(), "exceeded max depth") {
+		fmt.Println(err)
+		test.Error()
+	}
+}
@@ -0,0 +1,176 @@
+package jsonconfig
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Looks up the value of a named variable, reporting whether it was set. os.LookupEnv is a LookupFunc.
+type LookupFunc func(name string) (string, bool)
+
+// Describes an environment variable reference that couldn't be expanded.
+type EnvError struct {
+	// The dotted path of the string value containing the reference.
+	Path string
+	// The name of the variable, if the reference got far enough to have one.
+	Name    string
+	Message string
+}
+
+func (e *EnvError) Error() string {
+	return fmt.Sprintf("jsonconfig: %s: %s", e.Path, e.Message)
+}
+
+// Expands environment variable references within every string in the abstract value. Objects
+// and arrays are expanded in place. Objects are walked in key order so that the error returned
+// is always the same one.
+func expandEnv(path string, value interface{}, lookup LookupFunc) (interface{}, error) {
+	switch typedValue := value.(type) {
+	case string:
+		return expandEnvString(path, typedValue, lookup)
+	case []interface{}:
+		for i := range typedValue {
+			expanded, err := expandEnv(joinPath(path, strconv.Itoa(i)), typedValue[i], lookup)
+			if err != nil {
+				return nil, err
+			}
+			typedValue[i] = expanded
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(typedValue))
+		for key := range typedValue {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			expanded, err := expandEnv(joinPath(path, key), typedValue[key], lookup)
+			if err != nil {
+				return nil, err
+			}
+			typedValue[key] = expanded
+		}
+	}
+	return value, nil
+}
+
+// Expands the environment variable references within a single string.
+func expandEnvString(path string, value string, lookup LookupFunc) (string, error) {
+	if !strings.Contains(value, "$") {
+		return value, nil
+	}
+
+	var builder strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' || i+1 == len(value) {
+			builder.WriteByte(value[i])
+			continue
+		}
+
+		switch value[i+1] {
+		case '$':
+			builder.WriteByte('$')
+			i++
+		case '{':
+			end := closingBrace(value, i+2)
+			if end < 0 {
+				return "", &EnvError{Path: path, Message: fmt.Sprintf("unterminated reference %q", value[i:])}
+			}
+			expanded, err := expandReference(path, value[i+2:end], lookup)
+			if err != nil {
+				return "", err
+			}
+			builder.WriteString(expanded)
+			i = end
+		default:
+			builder.WriteByte('$')
+		}
+	}
+	return builder.String(), nil
+}
+
+// Finds the } that closes a reference whose contents start at start, skipping over any nested
+// references.
+func closingBrace(value string, start int) int {
+	depth := 0
+	for i := start; i < len(value); i++ {
+		switch {
+		case value[i] == '$' && i+1 < len(value) && value[i+1] == '$':
+			i++
+		case value[i] == '$' && i+1 < len(value) && value[i+1] == '{':
+			depth++
+			i++
+		case value[i] == '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// Expands the contents of a single ${...} reference.
+func expandReference(path string, reference string, lookup LookupFunc) (string, error) {
+	nameEnd := strings.IndexAny(reference, ":-?")
+	if nameEnd < 0 {
+		nameEnd = len(reference)
+	}
+	name := reference[:nameEnd]
+	if !isVariableName(name) {
+		return "", &EnvError{Path: path, Message: fmt.Sprintf("invalid variable name in ${%s}", reference)}
+	}
+
+	variable, set := lookup(name)
+	operator := reference[nameEnd:]
+	emptyCounts := strings.HasPrefix(operator, ":")
+	if emptyCounts {
+		operator = operator[1:]
+	}
+	usable := set && !(emptyCounts && variable == "")
+
+	switch {
+	case operator == "" && !emptyCounts:
+		return variable, nil
+	case strings.HasPrefix(operator, "-"):
+		if usable {
+			return variable, nil
+		}
+		return expandEnvString(path, operator[1:], lookup)
+	case strings.HasPrefix(operator, "?"):
+		if usable {
+			return variable, nil
+		}
+		message, err := expandEnvString(path, operator[1:], lookup)
+		if err != nil {
+			return "", err
+		}
+		if message == "" {
+			message = "required environment variable " + name + " is not set"
+		}
+		return "", &EnvError{Path: path, Name: name, Message: message}
+	default:
+		return "", &EnvError{Path: path, Name: name, Message: fmt.Sprintf("invalid reference ${%s}", reference)}
+	}
+}
+
+// Reports whether name is made up of letters, digits and underscores and doesn't start with a digit.
+func isVariableName(name string) bool {
+	for i, character := range name {
+		if !(character == '_' || ('a' <= character && character <= 'z') || ('A' <= character && character <= 'Z') ||
+			(i > 0 && '0' <= character && character <= '9')) {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// Adds a key to a dotted path. The key is escaped, so the path can be handed back to Get.
+func joinPath(path string, key string) string {
+	if path == "" {
+		return EscapeKey(key)
+	}
+	return path + "." + EscapeKey(key)
+}
@@ -0,0 +1,121 @@
+package jsonconfig_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/callum-ramage/jsonconfig"
+)
+
+// Builds a LookupFunc over a fixed set of variables so the tests don't depend on the real environment.
+func lookupFrom(variables map[string]string) jsonconfig.LookupFunc {
+	return func(name string) (string, bool) {
+		value, ok := variables[name]
+		return value, ok
+	}
+}
+
+func TestExpandEnv(test *testing.T) {
+	lookup := lookupFrom(map[string]string{
+		"DB_PORT":      "",
+		"DB_USER":      "app",
+		"REPLICA_HOST": "replica.internal",
+	})
+
+	config, err := jsonconfig.LoadAbstract("./configs/EnvConfig.conf", `{"db_name": "${DB_USER}_db"}`, jsonconfig.ExpandEnv(lookup))
+
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	expected := map[string]string{
+		"db_host":         "localhost",
+		"db_port":         "5432",
+		"db_url":          "postgres://app@localhost:5432/app",
+		"price":           "$5 and $5",
+		"${NOT_EXPANDED}": "keys are left alone",
+		"replicas.0":      "replica.internal",
+		"db_name":         "app_db",
+	}
+	for key, value := range expected {
+		if config[key].Str != value {
+			fmt.Println(key, config[key].Str)
+			test.Error()
+		}
+	}
+
+	// The same file without the option is left untouched
+	config, _ = jsonconfig.LoadAbstract("./configs/EnvConfig.conf", "")
+	if config["db_host"].Str != "${DB_HOST:-localhost}" {
+		fmt.Println(config["db_host"].Str)
+		test.Error()
+	}
+}
+
+func TestExpandEnvRequired(test *testing.T) {
+	_, err := jsonconfig.LoadAbstract("./configs/EnvConfig.conf", "", jsonconfig.ExpandEnv(lookupFrom(nil)))
+
+	var envError *jsonconfig.EnvError
+	if !errors.As(err, &envError) || envError.Path != "replicas.0" || envError.Name != "REPLICA_HOST" {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	if err.Error() != "jsonconfig: replicas.0: a replica host is needed" {
+		fmt.Println(err)
+		test.Error()
+	}
+
+	invalid := []string{
+		`{"a": "${UNCLOSED"}`,
+		`{"a": "${}"}`,
+		`{"a": "${NAME+alternate}"}`,
+		`{"a": "${EMPTY:?}"}`,
+	}
+	for _, source := range invalid {
+		if _, err := jsonconfig.LoadString(source, "", jsonconfig.ExpandEnv(lookupFrom(map[string]string{"EMPTY": ""}))); err == nil {
+			fmt.Println(source)
+			test.Error()
+		}
+	}
+}
+
+func TestExpandEnvLoad(test *testing.T) {
+	config := struct {
+		Db_host  string
+		Replicas []string
+	}{}
+
+	lookup := lookupFrom(map[string]string{"DB_HOST": "db.internal", "REPLICA_HOST": "replica.internal"})
+	if err := jsonconfig.Load("./configs/EnvConfig.conf", &config, jsonconfig.ExpandEnv(lookup)); err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	if config.Db_host != "db.internal" || config.Replicas[0] != "replica.internal" {
+		fmt.Println(config)
+		test.Error()
+	}
+}
+
+func TestExpandEnvLoadKeepsPrecision(test *testing.T) {
+	config := struct {
+		Example_id  int64
+		Example_max uint64
+	}{}
+
+	if err := jsonconfig.Load("./configs/PreciseNumberConfig.conf", &config, jsonconfig.ExpandEnv(lookupFrom(nil))); err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+	if config.Example_id != 9007199254740993 || config.Example_max != 18446744073709551615 {
+		fmt.Println(config)
+		test.Error()
+	}
+}
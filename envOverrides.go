@@ -0,0 +1,187 @@
+package jsonconfig
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Converts one part of a dotted path into the matching part of an environment variable name.
+func envName(key string) string {
+	return strings.Map(func(character rune) rune {
+		switch {
+		case 'a' <= character && character <= 'z':
+			return character - 'a' + 'A'
+		case ('A' <= character && character <= 'Z') || ('0' <= character && character <= '9'):
+			return character
+		default:
+			return '_'
+		}
+	}, key)
+}
+
+// Returns the keys of the configuration in order.
+func sortedKeys(config Configuration) []string {
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Overrides each member of an object from the environment. set is used to replace a member.
+func overrideMembersFromEnv(members Configuration, path string, prefix string, lookup LookupFunc, set func(string, JSONValue)) error {
+	for _, key := range sortedKeys(members) {
+		key := key
+		err := overrideFromEnv(members[key], joinPath(path, key), prefix+envName(key), lookup, func(value JSONValue) {
+			set(key, value)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Overrides the value from the environment variable called name if it's set, otherwise looks
+// for overrides of its members or elements. set is used to replace the value.
+func overrideFromEnv(value JSONValue, path string, name string, lookup LookupFunc, set func(JSONValue)) error {
+	if text, ok := lookup(name); ok {
+		replacement, err := coerceValue(value, text)
+		if err != nil {
+			return &OverrideError{Path: path, Source: "environment variable " + name, Err: err}
+		}
+		set(replacement)
+		return nil
+	}
+
+	switch value.Kind() {
+	case KindObject:
+		return overrideMembersFromEnv(value.Obj, path, name+"__", lookup, value.setMember)
+	case KindArray:
+		for i := range value.Arr {
+			i := i
+			err := overrideFromEnv(value.Arr[i], joinPath(path, strconv.Itoa(i)), name+"__"+strconv.Itoa(i), lookup, func(element JSONValue) {
+				value.setElement(i, element)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Overrides the field from the environment variable called name if it's set, otherwise looks
+// for overrides of its fields, elements or entries.
+func overrideFieldFromEnv(field reflect.Value, path string, name string, lookup LookupFunc) error {
+	if text, ok := lookup(name); ok {
+		for field.Kind() == reflect.Pointer {
+			if field.IsNil() {
+				field.Set(reflect.New(field.Type().Elem()))
+			}
+			field = field.Elem()
+		}
+		if err := setFromText(field, text); err != nil {
+			return &OverrideError{Path: path, Source: "environment variable " + name, Err: err}
+		}
+		return nil
+	}
+	return overrideChildrenFromEnv(field, path, name+"__", lookup)
+}
+
+// Looks for overrides of the fields of a struct, the elements of a slice or the entries of a
+// map. Nil pointers are left alone since there's nothing in them to override.
+func overrideChildrenFromEnv(value reflect.Value, path string, prefix string, lookup LookupFunc) error {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil
+		}
+		if value.Kind() == reflect.Interface {
+			return updateInterface(value, func(held reflect.Value) error {
+				return overrideChildrenFromEnv(held, path, prefix, lookup)
+			})
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		if value.CanAddr() && value.Addr().Type().Implements(textUnmarshalerType) {
+			return nil
+		}
+		for i := 0; i < value.NumField(); i++ {
+			fieldInfo := value.Type().Field(i)
+			if isPromoted(fieldInfo) {
+				if err := overrideChildrenFromEnv(value.Field(i), path, prefix, lookup); err != nil {
+					return err
+				}
+				continue
+			}
+
+			name, ok := jsonFieldName(fieldInfo)
+			if !ok || !fieldInfo.IsExported() {
+				continue
+			}
+			if err := overrideFieldFromEnv(value.Field(i), joinPath(path, name), prefix+envName(name), lookup); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			index := strconv.Itoa(i)
+			if err := overrideFieldFromEnv(value.Index(i), joinPath(path, index), prefix+index, lookup); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+		for _, key := range keys {
+			err := updateMapEntry(value, key.String(), func(entry reflect.Value) error {
+				return overrideFieldFromEnv(entry, joinPath(path, key.String()), prefix+envName(key.String()), lookup)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Calls update with a copy of the value held by an interface, then puts the copy back, as what an
+// interface holds can't be altered in place. Nothing is put back if update fails or the interface
+// can't be set.
+func updateInterface(value reflect.Value, update func(held reflect.Value) error) error {
+	held := reflect.New(value.Elem().Type()).Elem()
+	held.Set(value.Elem())
+	if err := update(held); err != nil {
+		return err
+	}
+	if value.CanSet() {
+		value.Set(held)
+	}
+	return nil
+}
+
+// Calls update with a copy of the entry under key in a map with string keys, then puts the copy
+// back, as map entries can't be altered in place. A missing entry starts out as the zero value.
+// Nothing is put back if update fails.
+func updateMapEntry(mapValue reflect.Value, key string, update func(entry reflect.Value) error) error {
+	mapKey := reflect.ValueOf(key).Convert(mapValue.Type().Key())
+	entry := reflect.New(mapValue.Type().Elem()).Elem()
+	if existing := mapValue.MapIndex(mapKey); existing.IsValid() {
+		entry.Set(existing)
+	}
+	if err := update(entry); err != nil {
+		return err
+	}
+	mapValue.SetMapIndex(mapKey, entry)
+	return nil
+}
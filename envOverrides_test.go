@@ -0,0 +1,165 @@
+package jsonconfig_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/callum-ramage/jsonconfig"
+)
+
+func TestEnvOverridesAbstract(test *testing.T) {
+	lookup := lookupFrom(map[string]string{
+		"APP_EXAMPLE_OBJECT__EXAMPLE_NUMBER": "7",
+		"APP_EXAMPLE_ARRAY__0":               "overridden value 0",
+		"APP_EXAMPLE_DEFAULT":                "5",
+		"APP_EXAMPLE_FLAG":                   "true",
+		"APP_UNKNOWN":                        "ignored",
+	})
+
+	config, err := jsonconfig.LoadAbstract("./configs/ExampleConfig.conf", `{"example_default": 4, "example_flag": false}`,
+		jsonconfig.EnvOverrides("APP_", lookup))
+
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	if config["example_object.example_number"].Num != 7 || config["example_object"].Obj["example_number"].Int != 7 {
+		fmt.Println(config["example_object.example_number"].Num)
+		test.Error()
+	}
+
+	if config["example_array.0"].Str != "overridden value 0" || config["example_array"].Value.([]interface{})[0] != "overridden value 0" {
+		fmt.Println(config["example_array.0"].Str)
+		test.Error()
+	}
+
+	// Values that only exist in the defaults can be overridden too
+	if config["example_default"].Int != 5 || !config["example_flag"].Bool {
+		fmt.Println(config["example_default"].Int, config["example_flag"].Bool)
+		test.Error()
+	}
+
+	if config["example_string"].Str != "string value" || config["unknown"].Kind() != jsonconfig.KindNull {
+		fmt.Println(config["example_string"].Str, config["unknown"].Value)
+		test.Error()
+	}
+}
+
+func TestEnvOverridesWholeValues(test *testing.T) {
+	lookup := lookupFrom(map[string]string{
+		"EXAMPLE_ARRAY":  `["a", "b"]`,
+		"EXAMPLE_OBJECT": `{"example_number": 1}`,
+	})
+
+	config, err := jsonconfig.LoadString(`{"example_array": [], "example_object": {"example_number": 5.3, "other": 1}}`, "",
+		jsonconfig.EnvOverrides("", lookup))
+
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	if len(config["example_array"].Arr) != 2 || config["example_array"].Arr[1].Str != "b" {
+		fmt.Println(config["example_array"].Arr)
+		test.Error()
+	}
+
+	if config.Get("example_object.example_number").Int != 1 || config.Get("example_object.other").Kind() != jsonconfig.KindNull {
+		fmt.Println(config["example_object"].Obj)
+		test.Error()
+	}
+}
+
+func TestEnvOverridesInvalid(test *testing.T) {
+	invalid := map[string]string{
+		"APP_EXAMPLE_OBJECT__EXAMPLE_NUMBER": "seven",
+		"APP_EXAMPLE_ARRAY":                  `{"not": "an array"}`,
+	}
+
+	for name, value := range invalid {
+		_, err := jsonconfig.LoadAbstract("./configs/ExampleConfig.conf", "",
+			jsonconfig.EnvOverrides("APP_", lookupFrom(map[string]string{name: value})))
+
+		var overrideError *jsonconfig.OverrideError
+		if !errors.As(err, &overrideError) || overrideError.Source != "environment variable "+name {
+			fmt.Println(err)
+			test.Error()
+		}
+	}
+}
+
+type overriddenObject struct {
+	Example_number float64
+	Timeout        time.Duration `json:"timeout"`
+}
+
+type overriddenConfiguration struct {
+	Example_string  string
+	Example_array   []string
+	Example_object  *overriddenObject
+	Example_default int
+	Example_missing *overriddenObject
+	Labels          map[string]string `json:"labels"`
+	Ignored         string            `json:"-"`
+}
+
+func TestEnvOverridesLoad(test *testing.T) {
+	lookup := lookupFrom(map[string]string{
+		"APP_EXAMPLE_STRING":                 "overridden string",
+		"APP_EXAMPLE_ARRAY__0":               "overridden value 0",
+		"APP_EXAMPLE_OBJECT__EXAMPLE_NUMBER": "7.5",
+		"APP_EXAMPLE_OBJECT__TIMEOUT":        "30s",
+		"APP_EXAMPLE_DEFAULT":                "5",
+		"APP_LABELS__TEAM":                   "platform",
+		"APP_IGNORED":                        "ignored",
+	})
+
+	config := overriddenConfiguration{Example_default: 4, Labels: map[string]string{"team": "unknown"}}
+	if err := jsonconfig.Load("./configs/ExampleConfig.conf", &config, jsonconfig.EnvOverrides("APP_", lookup)); err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	if config.Example_string != "overridden string" || config.Example_array[0] != "overridden value 0" {
+		fmt.Println(config.Example_string, config.Example_array)
+		test.Error()
+	}
+
+	if config.Example_object.Example_number != 7.5 || config.Example_object.Timeout != 30*time.Second {
+		fmt.Println(config.Example_object)
+		test.Error()
+	}
+
+	if config.Example_default != 5 || config.Labels["team"] != "platform" || config.Ignored != "" || config.Example_missing != nil {
+		fmt.Println(config.Example_default, config.Labels, config.Ignored, config.Example_missing)
+		test.Error()
+	}
+
+	// A struct held by an interface can be overridden too
+	held := struct {
+		Example_missing interface{}
+	}{Example_missing: overriddenObject{}}
+	lookup = lookupFrom(map[string]string{"APP_EXAMPLE_MISSING__TIMEOUT": "5s"})
+	if err := jsonconfig.Load("./configs/ExampleConfig.conf", &held, jsonconfig.EnvOverrides("APP_", lookup)); err != nil {
+		fmt.Println(err)
+		test.Error()
+	} else if object, _ := held.Example_missing.(overriddenObject); object.Timeout != 5*time.Second {
+		fmt.Println(held.Example_missing)
+		test.Error()
+	}
+
+	lookup = lookupFrom(map[string]string{"APP_EXAMPLE_DEFAULT": "five"})
+	err := jsonconfig.Load("./configs/ExampleConfig.conf", &config, jsonconfig.EnvOverrides("APP_", lookup))
+
+	var overrideError *jsonconfig.OverrideError
+	if !errors.As(err, &overrideError) || overrideError.Path != "Example_default" {
+		fmt.Println(err)
+		test.Error()
+	}
+}
@@ -0,0 +1,23 @@
+package jsonconfig
+
+import "strings"
+
+// Joins the messages of a list of errors, one to a line, for the Error method of the list.
+func joinErrors[E error](errs []E) string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Returns nil rather than an empty list so that callers can compare the result with nil.
+func errorsOrNil[L interface {
+	~[]E
+	error
+}, E error](errs L) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
@@ -0,0 +1,139 @@
+package jsonconfig
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Is reported when an assignment names a path that doesn't exist in the configuration.
+var ErrUnknownPath = errors.New("no such path in the configuration")
+
+// A single assignment of a value to a dotted path, such as example_object.example_number=7.
+type Assignment struct {
+	Path  string
+	Value string
+}
+
+func (a Assignment) String() string {
+	return a.Path + "=" + a.Value
+}
+
+// A list of assignments of the form path=value, typically given on the command line with a
+// flag such as --set example_object.example_number=7. Assignments implements flag.Value, so
+// it can be registered with flag.Var, or use SetFlag to do that for you.
+type Assignments []Assignment
+
+// Registers a repeatable flag that collects assignments. If flags is nil the flag is added to
+// flag.CommandLine.
+//
+//	assignments := jsonconfig.SetFlag(nil, "set", "override a configuration value")
+//	flag.Parse()
+//	config, err := jsonconfig.LoadAbstract("./configs/ExampleConfig.conf", "")
+//	...
+//	err = assignments.Apply(config)
+func SetFlag(flags *flag.FlagSet, name string, usage string) *Assignments {
+	if flags == nil {
+		flags = flag.CommandLine
+	}
+	assignments := &Assignments{}
+	flags.Var(assignments, name, usage)
+	return assignments
+}
+
+// Parses each argument as a path=value assignment.
+func ParseAssignments(args []string) (Assignments, error) {
+	assignments := Assignments{}
+	for _, arg := range args {
+		if err := assignments.Set(arg); err != nil {
+			return nil, err
+		}
+	}
+	return assignments, nil
+}
+
+func (a *Assignments) String() string {
+	if a == nil {
+		return ""
+	}
+	parts := make([]string, len(*a))
+	for i, assignment := range *a {
+		parts[i] = assignment.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// Adds a single path=value assignment. The value is everything after the first =.
+func (a *Assignments) Set(text string) error {
+	path, value, found := strings.Cut(text, "=")
+	if !found || path == "" {
+		return fmt.Errorf("jsonconfig: %q is not of the form path=value", text)
+	}
+	*a = append(*a, Assignment{Path: path, Value: value})
+	return nil
+}
+
+// Applies every assignment to the configuration, converting each value to the type of the value
+// it replaces. Every path must already exist. All of the assignments that fail are reported
+// together as OverrideErrors, and the rest are still applied. A configuration that has been
+// collapsed is collapsed again afterwards so the dotted keys see the new values.
+func (a Assignments) Apply(config Configuration) error {
+	collapsed := config.collapsed()
+	if collapsed {
+		config.uncollapse()
+	}
+
+	var failures OverrideErrors
+	for _, assignment := range a {
+		existing, replace, ok := config.resolve(assignment.Path)
+		if !ok {
+			failures = append(failures, assignment.failure(ErrUnknownPath))
+			continue
+		}
+
+		value, err := coerceValue(existing, assignment.Value)
+		if err != nil {
+			failures = append(failures, assignment.failure(err))
+			continue
+		}
+		replace(value)
+	}
+
+	if collapsed {
+		config.Collapse()
+	}
+	return errorsOrNil(failures)
+}
+
+// Applies every assignment to target, which must be a pointer to a struct or map such as the
+// one handed to Load. Paths are matched against fields in the same way encoding/json matches
+// keys. All of the assignments that fail are reported together as OverrideErrors, and the rest
+// are still applied.
+func (a Assignments) ApplyTo(target interface{}) error {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return fmt.Errorf("jsonconfig: ApplyTo needs a non-nil pointer, not %T", target)
+	}
+
+	var failures OverrideErrors
+	for _, assignment := range a {
+		if err := assignField(value.Elem(), splitPath(assignment.Path), assignment.Value); err != nil {
+			failures = append(failures, assignment.failure(err))
+		}
+	}
+	return errorsOrNil(failures)
+}
+
+// Wraps an error applying the assignment.
+func (a Assignment) failure(err error) *OverrideError {
+	return &OverrideError{Path: a.Path, Source: "assignment " + a.String(), Err: err}
+}
+
+// A list of every override that couldn't be applied.
+type OverrideErrors []*OverrideError
+
+func (e OverrideErrors) Error() string {
+	return joinErrors(e)
+}
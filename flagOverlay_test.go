@@ -0,0 +1,160 @@
+package jsonconfig_test
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"testing"
+
+	"github.com/callum-ramage/jsonconfig"
+)
+
+func TestSetFlag(test *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	assignments := jsonconfig.SetFlag(flags, "set", "override a configuration value")
+
+	err := flags.Parse([]string{
+		"--set", "example_object.example_number=7",
+		"--set=example_array.0=a=b",
+		"-set", "example_string= spaced ",
+	})
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	if assignments.String() != "example_object.example_number=7,example_array.0=a=b,example_string= spaced " {
+		fmt.Println(assignments.String())
+		test.Error()
+	}
+
+	config, err := jsonconfig.LoadAbstract("./configs/ExampleConfig.conf", "")
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	if err = assignments.Apply(config); err != nil {
+		fmt.Println(err)
+		test.Error()
+	}
+
+	// Both the tree and the collapsed keys see the new values
+	if config["example_object"].Obj["example_number"].Num != 7 || config["example_object.example_number"].Num != 7 {
+		fmt.Println(config["example_object"].Obj["example_number"].Num, config["example_object.example_number"].Num)
+		test.Error()
+	}
+
+	if config["example_array"].Arr[0].Str != "a=b" || config["example_array.0"].Str != "a=b" {
+		fmt.Println(config["example_array"].Arr[0].Str, config["example_array.0"].Str)
+		test.Error()
+	}
+
+	if config["example_string"].Str != " spaced " {
+		fmt.Printf("%q\n", config["example_string"].Str)
+		test.Error()
+	}
+
+	if err = flags.Parse([]string{"--set", "no equals sign"}); err == nil {
+		test.Error()
+	}
+}
+
+func TestAssignmentsApplyErrors(test *testing.T) {
+	assignments, err := jsonconfig.ParseAssignments([]string{
+		"example_object.missing=1",
+		"example_object.example_number=seven",
+		"example_array.3=out of range",
+		"example_string=still applied",
+	})
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	config, _ := jsonconfig.LoadAbstractNoCollapse("./configs/ExampleConfig.conf", "")
+	err = assignments.Apply(config)
+
+	var failures jsonconfig.OverrideErrors
+	if !errors.As(err, &failures) || len(failures) != 3 {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	if !errors.Is(failures[0], jsonconfig.ErrUnknownPath) || failures[0].Path != "example_object.missing" || !errors.Is(failures[2], jsonconfig.ErrUnknownPath) {
+		fmt.Println(failures)
+		test.Error()
+	}
+
+	if config["example_string"].Str != "still applied" {
+		fmt.Println(config["example_string"].Str)
+		test.Error()
+	}
+}
+
+func TestAssignmentsApplyTo(test *testing.T) {
+	assignments, _ := jsonconfig.ParseAssignments([]string{
+		"example_object.example_number=7",
+		"example_object.timeout=1m",
+		"example_array.0=overridden value 0",
+		"EXAMPLE_DEFAULT=5",
+		"example_missing.example_number=2",
+		"labels.team=platform",
+	})
+
+	config := overriddenConfiguration{}
+	if err := jsonconfig.Load("./configs/ExampleConfig.conf", &config); err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	if err := assignments.ApplyTo(&config); err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	if config.Example_object.Example_number != 7 || config.Example_object.Timeout.Minutes() != 1 || config.Example_array[0] != "overridden value 0" {
+		fmt.Println(config.Example_object, config.Example_array)
+		test.Error()
+	}
+
+	if config.Example_default != 5 || config.Example_missing.Example_number != 2 || config.Labels["team"] != "platform" {
+		fmt.Println(config.Example_default, config.Example_missing, config.Labels)
+		test.Error()
+	}
+
+	abstract := map[string]interface{}{}
+	if err := jsonconfig.Load("./configs/ExampleConfig.conf", &abstract); err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	assignments, _ = jsonconfig.ParseAssignments([]string{"example_object.example_number=7", "example_missing.timeout=\"1m\""})
+	if err := assignments.ApplyTo(&abstract); err != nil {
+		fmt.Println(err)
+		test.Error()
+	}
+
+	object, _ := abstract["example_object"].(map[string]interface{})
+	missing, _ := abstract["example_missing"].(map[string]interface{})
+	if fmt.Sprint(object["example_number"]) != "7" || missing["timeout"] != "1m" {
+		fmt.Println(abstract)
+		test.Error()
+	}
+
+	assignments, _ = jsonconfig.ParseAssignments([]string{"example_objct.example_number=7", "ignored=1", "example_default=x"})
+	err := assignments.ApplyTo(&config)
+
+	var failures jsonconfig.OverrideErrors
+	if !errors.As(err, &failures) || len(failures) != 3 {
+		fmt.Println(err)
+		test.Error()
+	}
+}
@@ -0,0 +1,107 @@
+package jsonconfig
+
+import (
+	"sync/atomic"
+)
+
+// A configuration along with the version it was given when it was put into a Holder. A Snapshot
+// is never changed once it's been created, so it can be passed between goroutines freely.
+type Snapshot struct {
+	Config  Configuration
+	Version uint64
+}
+
+// Returns the value at the path in the snapshot's configuration, in the same way as Configuration.Get.
+func (snapshot *Snapshot) Get(path string) JSONValue {
+	return snapshot.Config.Get(path)
+}
+
+// Holds the current configuration for a program that swaps it out while running, such as after a
+// reload. Reads are lock free and never see a partly replaced configuration, as the holder only
+// ever hands out whole Snapshots.
+//
+// A Configuration is a plain map, so it must not be modified after it has been given to the holder.
+// Make a new one and Replace the old one instead. The zero Holder is ready to use, and holds an
+// empty configuration with version 0 until something is put in it.
+//
+//	holder := jsonconfig.NewHolder(config)
+//
+//	snapshot := holder.Snapshot()
+//	port := snapshot.Get("server.port").Integer()
+//	...
+//	if holder.Stale(snapshot) {
+//	  // The configuration was replaced while we were working
+//	}
+type Holder struct {
+	current atomic.Pointer[Snapshot]
+}
+
+// Creates a holder with config as its first snapshot, which has version 1.
+func NewHolder(config Configuration) *Holder {
+	holder := &Holder{}
+	holder.Replace(config)
+	return holder
+}
+
+// Returns the current snapshot. Hold on to the snapshot, rather than the holder, for as long as
+// you need a consistent view of the configuration.
+func (h *Holder) Snapshot() *Snapshot {
+	if snapshot := h.current.Load(); snapshot != nil {
+		return snapshot
+	}
+	return &Snapshot{Config: Configuration{}}
+}
+
+// Returns the current configuration.
+func (h *Holder) Config() Configuration {
+	return h.Snapshot().Config
+}
+
+// Returns the version of the current configuration. Versions start at 1 and go up by one with
+// each replacement, while a holder that has never had a configuration is at version 0.
+func (h *Holder) Version() uint64 {
+	return h.Snapshot().Version
+}
+
+// Checks if the snapshot has since been replaced. A nil snapshot is stale once the holder has a
+// configuration.
+func (h *Holder) Stale(snapshot *Snapshot) bool {
+	if snapshot == nil {
+		return h.Version() != 0
+	}
+	return h.Version() != snapshot.Version
+}
+
+// Makes config the current configuration, returning the new snapshot.
+func (h *Holder) Replace(config Configuration) *Snapshot {
+	for {
+		old := h.current.Load()
+		snapshot := &Snapshot{Config: config, Version: 1}
+		if old != nil {
+			snapshot.Version = old.Version + 1
+		}
+		if h.current.CompareAndSwap(old, snapshot) {
+			return snapshot
+		}
+	}
+}
+
+// Makes config the current configuration only if old is still the current snapshot, which stops
+// two goroutines that both started from old from overwriting each other's changes. The new
+// snapshot is returned, or nil if old was stale. A nil old, or the snapshot of a holder that has
+// never had a configuration, only succeeds while the holder is still empty.
+func (h *Holder) CompareAndReplace(old *Snapshot, config Configuration) *Snapshot {
+	expected := old
+	if old != nil && old.Version == 0 {
+		expected = nil
+	}
+
+	snapshot := &Snapshot{Config: config, Version: 1}
+	if expected != nil {
+		snapshot.Version = expected.Version + 1
+	}
+	if h.current.CompareAndSwap(expected, snapshot) {
+		return snapshot
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package jsonconfig_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/callum-ramage/jsonconfig"
+)
+
+func TestHolderReplace(test *testing.T) {
+	first, _ := jsonconfig.LoadString(`{"port": 80}`, "")
+	second, _ := jsonconfig.LoadString(`{"port": 8080}`, "")
+
+	holder := jsonconfig.NewHolder(first)
+	snapshot := holder.Snapshot()
+	if snapshot.Version != 1 || snapshot.Get("port").Integer() != 80 || holder.Stale(snapshot) {
+		fmt.Println(snapshot.Version, snapshot.Config)
+		test.Error()
+	}
+
+	replaced := holder.Replace(second)
+	if replaced.Version != 2 || holder.Version() != 2 || holder.Config().Get("port").Integer() != 8080 {
+		fmt.Println(replaced.Version, holder.Config())
+		test.Error()
+	}
+
+	// The old snapshot still shows the old configuration
+	if !holder.Stale(snapshot) || snapshot.Get("port").Integer() != 80 {
+		fmt.Println(snapshot.Config)
+		test.Error()
+	}
+}
+
+func TestHolderCompareAndReplace(test *testing.T) {
+	first, _ := jsonconfig.LoadString(`{"port": 80}`, "")
+	second, _ := jsonconfig.LoadString(`{"port": 8080}`, "")
+
+	holder := jsonconfig.NewHolder(first)
+	snapshot := holder.Snapshot()
+	holder.Replace(first)
+
+	if holder.CompareAndReplace(snapshot, second) != nil || holder.Version() != 2 {
+		fmt.Println(holder.Version())
+		test.Error()
+	}
+
+	if replaced := holder.CompareAndReplace(holder.Snapshot(), second); replaced == nil || replaced.Version != 3 {
+		fmt.Println(replaced)
+		test.Error()
+	}
+}
+
+func TestHolderConcurrentReplace(test *testing.T) {
+	config, _ := jsonconfig.LoadString(`{"port": 80}`, "")
+	holder := jsonconfig.NewHolder(config)
+
+	var group sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		group.Add(2)
+		go func() {
+			defer group.Done()
+			for j := 0; j < 100; j++ {
+				holder.Replace(config)
+			}
+		}()
+		go func() {
+			defer group.Done()
+			for j := 0; j < 100; j++ {
+				if holder.Snapshot().Get("port").Integer() != 80 {
+					test.Error()
+				}
+			}
+		}()
+	}
+	group.Wait()
+
+	if holder.Version() != 801 {
+		fmt.Println(holder.Version())
+		test.Error()
+	}
+}
+
+func TestHolderZeroValue(test *testing.T) {
+	var holder jsonconfig.Holder
+	if holder.Config() == nil || len(holder.Config()) != 0 || holder.Version() != 0 || holder.Stale(nil) {
+		test.Error()
+	}
+
+	config, _ := jsonconfig.LoadString(`{"port": 80}`, "")
+	empty := holder.Snapshot()
+	if snapshot := holder.CompareAndReplace(empty, config); snapshot == nil || snapshot.Version != 1 {
+		fmt.Println(snapshot)
+		test.Error()
+	}
+	if holder.CompareAndReplace(nil, config) != nil || !holder.Stale(empty) || holder.Config()["port"].Int != 80 {
+		test.Error()
+	}
+}
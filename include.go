@@ -0,0 +1,176 @@
+package jsonconfig
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// The key of the directive that pulls other files into an object.
+const includeKey = "$include"
+
+// How deeply includes can be nested when MaxIncludeDepth isn't used.
+const defaultMaxIncludeDepth = 16
+
+// Describes a problem loading a file that was pulled in by an $include directive.
+type IncludeError struct {
+	// The files that were being loaded, from the one handed to LoadAbstract through to the one
+	// with the problem.
+	Chain []string
+	Err   error
+}
+
+func (e *IncludeError) Error() string {
+	return fmt.Sprintf("jsonconfig: include %s: %v", strings.Join(e.Chain, " -> "), e.Err)
+}
+
+func (e *IncludeError) Unwrap() error {
+	return e.Err
+}
+
+// Loads a file as abstract JSON values, resolving any $include directives within it. chain holds
+// the files that led to this one, and every file loaded is added to files. path is where the
+// file's contents end up in the configuration, and known holds the defaults for that spot when
+// loading strictly. Unknown keys are returned as UnknownKeyErrors alongside the values, once
+// every file has been checked.
+func loadIncludingFile(filename string, options *loadOptions, chain []string, files *[]string, path string, known interface{}) (map[string]interface{}, error) {
+	absolute, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, previous := range chain {
+		if previousAbsolute, _ := filepath.Abs(previous); previousAbsolute == absolute {
+			return nil, &IncludeError{Chain: appendPath(chain, filename), Err: fmt.Errorf("include cycle")}
+		}
+	}
+	chain = appendPath(chain, filename)
+	if len(chain) > options.maxIncludeDepth+1 {
+		return nil, &IncludeError{Chain: chain, Err: fmt.Errorf("includes are nested more than %d deep", options.maxIncludeDepth)}
+	}
+
+	source, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, includeFailure(chain, err)
+	}
+	if files != nil {
+		*files = append(*files, absolute)
+	}
+
+	untypedMap := map[string]interface{}{}
+	if err = decode(filename, bytes.NewReader(source), &untypedMap, options); err != nil {
+		return nil, includeFailure(chain, err)
+	}
+
+	var unknown UnknownKeyErrors
+	if known != nil {
+		var paths []string
+		unknownKeys(untypedMap, known, "", &paths)
+		unknown = locateUnknownKeys(filename, source, options, path, paths)
+	}
+
+	err = resolveIncludes(untypedMap, filepath.Dir(filename), options, chain, files, path, known)
+	if err = collectUnknownKeys(err, &unknown); err != nil {
+		return nil, err
+	}
+	return untypedMap, errorsOrNil(unknown)
+}
+
+// Replaces every $include directive within value with the contents of the files it names.
+// Members already in the object take precedence over included ones, and later files in a list
+// take precedence over earlier ones. Objects found in both are merged. path and known describe
+// value in the same way as for loadIncludingFile, and unknown keys in the included files are
+// returned as UnknownKeyErrors.
+func resolveIncludes(value interface{}, directory string, options *loadOptions, chain []string, files *[]string, path string, known interface{}) error {
+	var unknown UnknownKeyErrors
+	switch typedValue := value.(type) {
+	case []interface{}:
+		// Arrays aren't checked for unknown keys
+		for i, element := range typedValue {
+			err := resolveIncludes(element, directory, options, chain, files, joinPath(path, strconv.Itoa(i)), nil)
+			if err = collectUnknownKeys(err, &unknown); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		knownObject, _ := known.(map[string]interface{})
+		for _, key := range sortedMapKeys(typedValue) {
+			if key != includeKey {
+				err := resolveIncludes(typedValue[key], directory, options, chain, files, joinPath(path, key), knownObject[key])
+				if err = collectUnknownKeys(err, &unknown); err != nil {
+					return err
+				}
+			}
+		}
+
+		include, exists := typedValue[includeKey]
+		if !exists {
+			return errorsOrNil(unknown)
+		}
+		delete(typedValue, includeKey)
+
+		var names []string
+		switch typedInclude := include.(type) {
+		case string:
+			names = []string{typedInclude}
+		case []interface{}:
+			for _, name := range typedInclude {
+				typedName, ok := name.(string)
+				if !ok {
+					return &IncludeError{Chain: chain, Err: fmt.Errorf("%s must name files with strings", includeKey)}
+				}
+				names = append(names, typedName)
+			}
+		default:
+			return &IncludeError{Chain: chain, Err: fmt.Errorf("%s must be a string or an array of strings", includeKey)}
+		}
+
+		for i := len(names) - 1; i >= 0; i-- {
+			includeName := names[i]
+			if !filepath.IsAbs(includeName) {
+				includeName = filepath.Join(directory, includeName)
+			}
+			included, err := loadIncludingFile(includeName, options, chain, files, path, known)
+			if err = collectUnknownKeys(err, &unknown); err != nil {
+				return err
+			}
+			mergeMissing(typedValue, included)
+		}
+	}
+	return errorsOrNil(unknown)
+}
+
+// Copies the members of other into object where they don't already exist, merging objects that
+// exist in both.
+func mergeMissing(object map[string]interface{}, other map[string]interface{}) {
+	for key, otherValue := range other {
+		value, exists := object[key]
+		if !exists {
+			object[key] = otherValue
+			continue
+		}
+
+		valueObject, valueIsObject := value.(map[string]interface{})
+		otherObject, otherIsObject := otherValue.(map[string]interface{})
+		if valueIsObject && otherIsObject {
+			mergeMissing(valueObject, otherObject)
+		}
+	}
+}
+
+// Wraps an error from a file that was included by another, naming the chain of includes.
+// Errors from the file handed to LoadAbstract itself are returned as they are.
+func includeFailure(chain []string, err error) error {
+	if len(chain) < 2 {
+		return err
+	}
+	return &IncludeError{Chain: chain, Err: err}
+}
+
+// Appends to a copy of the chain so that sibling includes don't share a backing array.
+func appendPath(chain []string, filename string) []string {
+	return append(append([]string{}, chain...), filename)
+}
@@ -0,0 +1,77 @@
+package jsonconfig_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/callum-ramage/jsonconfig"
+)
+
+func TestInclude(test *testing.T) {
+	config, err := jsonconfig.LoadAbstract("./configs/include/Main.conf", "")
+
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	expected := map[string]interface{}{
+		"database.host":      "localhost",
+		"database.port":      float64(5433),
+		"database.pool.size": float64(10),
+		"logging.level":      "debug",
+		"logging.format":     "json",
+		"name":               "main",
+	}
+	for path, value := range expected {
+		if config[path].Value != value {
+			fmt.Println(path, config[path].Value)
+			test.Error()
+		}
+	}
+
+	if _, exists := config["$include"]; exists {
+		test.Error()
+	}
+
+	if _, exists := config["database"].Obj["$include"]; exists {
+		test.Error()
+	}
+}
+
+func TestIncludeErrors(test *testing.T) {
+	_, err := jsonconfig.LoadAbstract("./configs/include/CycleA.conf", "")
+
+	var includeError *jsonconfig.IncludeError
+	if !errors.As(err, &includeError) || len(includeError.Chain) != 3 {
+		fmt.Println(err)
+		test.Error()
+	}
+
+	if err.Error() != "jsonconfig: include ./configs/include/CycleA.conf -> configs/include/CycleB.conf -> configs/include/CycleA.conf: include cycle" {
+		fmt.Println(err)
+		test.Error()
+	}
+
+	_, err = jsonconfig.LoadAbstract("./configs/include/Main.conf", "", jsonconfig.MaxIncludeDepth(1))
+	if !errors.As(err, &includeError) || len(includeError.Chain) != 3 {
+		fmt.Println(err)
+		test.Error()
+	}
+
+	// Parse errors name the file they're in as well as the chain that got there
+	_, err = jsonconfig.LoadAbstractNoCollapse("./configs/include/Broken.conf", "")
+
+	var parseError *jsonconfig.ParseError
+	if !errors.As(err, &includeError) || !errors.As(err, &parseError) || parseError.Filename != "configs/include/nested/Invalid.conf" || parseError.Line != 2 {
+		fmt.Println(err)
+		test.Error()
+	}
+
+	if _, err = jsonconfig.LoadString(`{"$include": 5}`, ""); err != nil {
+		fmt.Println("strings don't resolve includes", err)
+		test.Error()
+	}
+}
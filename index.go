@@ -0,0 +1,92 @@
+package jsonconfig
+
+import (
+	"sort"
+	"strconv"
+)
+
+// A flat view of a configuration, mapping the dotted path of every value within it to the value.
+// It gives the same lookups as a collapsed configuration without adding keys to the
+// configuration or the objects within it, so the configuration can still be ranged over.
+//
+//	config, index, err := jsonconfig.LoadIndexed("./configs/ExampleConfig.conf", "")
+//	if err != nil {
+//	  return
+//	}
+//
+//	fmt.Println(index["example_object.example_number"].Num)
+//	for key := range config {
+//	  fmt.Println(key)
+//	}
+//
+// As with Collapse, a key that contains dots takes precedence over a nested path that spells the
+// same thing, so for
+//
+//	{
+//	  "example": {
+//	    "collision": "ignored"
+//	  },
+//	  "example.collision": "used"
+//	}
+//
+// index["example.collision"] is "used".
+type Index map[string]JSONValue
+
+// Builds an Index of the configuration. The configuration isn't altered, and any keys already
+// added to it by Collapse are ignored.
+func (config Configuration) Index() Index {
+	index := Index{}
+	// How many keys were joined to make each path, so that a more literal path can win
+	depths := map[string]int{}
+	for _, key := range sortedKeys(config) {
+		if !config.addedByCollapse(key) {
+			index.add(key, config[key], 1, depths)
+		}
+	}
+	return index
+}
+
+// Adds the value and everything within it to the index.
+func (index Index) add(path string, value JSONValue, depth int, depths map[string]int) {
+	if existing, exists := depths[path]; !exists || depth < existing {
+		index[path] = value
+		depths[path] = depth
+	}
+
+	for _, key := range sortedKeys(value.Obj) {
+		if !value.Obj.addedByCollapse(key) {
+			index.add(path+"."+key, value.Obj[key], depth+1, depths)
+		}
+	}
+	for i, element := range value.Arr {
+		index.add(path+"."+strconv.Itoa(i), element, depth+1, depths)
+	}
+}
+
+// Returns the value at the dotted path, or a null JSONValue if there isn't one.
+func (index Index) Get(path string) JSONValue {
+	if value, ok := index[path]; ok {
+		return value
+	}
+	return NewJSONValue(nil)
+}
+
+// Returns every path in the index in sorted order.
+func (index Index) Paths() []string {
+	paths := make([]string, 0, len(index))
+	for path := range index {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Loads the file in the same way as LoadAbstractNoCollapse, along with an Index of it. This gives
+// dotted path lookups from the index while leaving the configuration clean to range over.
+func LoadIndexed(filename string, defaults string, options ...LoadOption) (Configuration, Index, error) {
+	config, err := LoadAbstractNoCollapse(filename, defaults, options...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return config, config.Index(), nil
+}
@@ -0,0 +1,62 @@
+package jsonconfig_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/callum-ramage/jsonconfig"
+)
+
+func TestLoadIndexed(test *testing.T) {
+	config, index, err := jsonconfig.LoadIndexed("./configs/ExampleConfig.conf", `{"example_object": {"example_default": true}}`)
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	if index["example_object.example_number"].Num != 5.3 || index.Get("example_array.0").Str != "array value 0" || !index.Get("example_object.example_default").Bool {
+		fmt.Println(index)
+		test.Error()
+	}
+	if index.Get("missing").Value != nil {
+		test.Error()
+	}
+
+	// Neither the configuration nor the objects within it gain any keys
+	if len(config) != 3 || len(config["example_object"].Obj) != 2 {
+		fmt.Println(config)
+		test.Error()
+	}
+
+	expected := "example_array example_array.0 example_object example_object.example_default example_object.example_number example_string"
+	if strings.Join(index.Paths(), " ") != expected {
+		fmt.Println(index.Paths())
+		test.Error()
+	}
+}
+
+func TestIndexPrefersLiteralKeys(test *testing.T) {
+	config, _ := jsonconfig.LoadString(`{
+		"example": {"collision": "ignored", "nested": {"a": 1}},
+		"example.collision": "used",
+		"example.nested.a": 2
+	}`, "")
+
+	index := config.Index()
+	if index["example.collision"].Str != "used" || index["example.nested.a"].Num != 2 {
+		fmt.Println(index)
+		test.Error()
+	}
+}
+
+func TestIndexIgnoresCollapse(test *testing.T) {
+	collapsed, _ := jsonconfig.LoadAbstract("./configs/ExampleConfig.conf", "")
+	_, index, _ := jsonconfig.LoadIndexed("./configs/ExampleConfig.conf", "")
+
+	if len(collapsed.Index()) != len(index) {
+		fmt.Println(collapsed.Index().Paths(), index.Paths())
+		test.Error()
+	}
+}
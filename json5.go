@@ -0,0 +1,587 @@
+package jsonconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Describes why a JSON5 document couldn't be parsed and the byte offset the problem was found at.
+type json5SyntaxError struct {
+	message string
+	Offset  int64
+}
+
+func (e *json5SyntaxError) Error() string {
+	return e.message
+}
+
+// How deeply arrays and objects can be nested, which matches the limit encoding/json applies. A
+// recursive descent parser would otherwise run out of stack on deeply nested input.
+const maxNestingDepth = 10000
+
+// A recursive descent parser for JSON5 (https://spec.json5.org). It produces the same abstract
+// values as encoding/json does when decoding into an interface{}, so the result can be handed
+// straight to ConvertMap.
+type json5Parser struct {
+	data           []byte
+	pos            int
+	preciseNumbers bool
+	hashComments   bool
+	// How many arrays and objects enclose the current position
+	depth int
+	// When set, the offset of every object key is recorded by its dotted path
+	keys map[string]int64
+	path string
+}
+
+// Parses a single JSON5 value from data. Anything other than whitespace and comments following
+// the value is an error. If preciseNumbers is set, finite numbers are returned as a json.Number
+// holding their exact decimal value, and if hashComments is set # begins a comment as // does.
+func parseJSON5(data []byte, preciseNumbers bool, hashComments bool) (interface{}, error) {
+	parser := json5Parser{data: data, preciseNumbers: preciseNumbers, hashComments: hashComments}
+	// A byte order mark is whitespace as far as JSON5 is concerned
+	if err := parser.skipWhitespace(); err != nil {
+		return nil, err
+	}
+
+	value, err := parser.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := parser.skipWhitespace(); err != nil {
+		return nil, err
+	}
+	if parser.pos != len(parser.data) {
+		return nil, parser.errorf("invalid character %s after top-level value", parser.describe())
+	}
+	return value, nil
+}
+
+// Builds a syntax error for the current position.
+func (p *json5Parser) errorf(format string, args ...interface{}) error {
+	return &json5SyntaxError{message: "json5: " + fmt.Sprintf(format, args...), Offset: int64(p.pos)}
+}
+
+// Quotes the character at the current position for use in an error message.
+func (p *json5Parser) describe() string {
+	if p.pos >= len(p.data) {
+		return "end of input"
+	}
+	character, _ := utf8.DecodeRune(p.data[p.pos:])
+	return strconv.QuoteRune(character)
+}
+
+// Returns the rune at the current position along with its width, or -1 at the end of the input.
+func (p *json5Parser) peek() (rune, int) {
+	if p.pos >= len(p.data) {
+		return -1, 0
+	}
+	if p.data[p.pos] < utf8.RuneSelf {
+		return rune(p.data[p.pos]), 1
+	}
+	return utf8.DecodeRune(p.data[p.pos:])
+}
+
+// Reports whether the rune counts as whitespace in JSON5.
+func isJSON5Whitespace(character rune) bool {
+	switch character {
+	case '\t', '\n', '\v', '\f', '\r', ' ', '\u00a0', '\u2028', '\u2029', '\ufeff':
+		return true
+	}
+	return unicode.Is(unicode.Zs, character)
+}
+
+// Reports whether the rune ends a line in JSON5.
+func isJSON5LineTerminator(character rune) bool {
+	return character == '\n' || character == '\r' || character == '\u2028' || character == '\u2029'
+}
+
+// Advances past any whitespace, //comments, /* block comments */ and #comments if they're allowed.
+func (p *json5Parser) skipWhitespace() error {
+	for {
+		character, width := p.peek()
+		switch {
+		case isJSON5Whitespace(character):
+			p.pos += width
+		case character == '#' && p.hashComments, character == '/' && p.pos+1 < len(p.data) && p.data[p.pos+1] == '/':
+			for character != -1 && !isJSON5LineTerminator(character) {
+				p.pos += width
+				character, width = p.peek()
+			}
+		case character == '/' && p.pos+1 < len(p.data) && p.data[p.pos+1] == '*':
+			end := strings.Index(string(p.data[p.pos+2:]), "*/")
+			if end < 0 {
+				return p.errorf("unterminated block comment")
+			}
+			p.pos += end + 4
+		default:
+			return nil
+		}
+	}
+}
+
+// Parses whichever value starts at the current position.
+func (p *json5Parser) parseValue() (interface{}, error) {
+	character, _ := p.peek()
+	switch {
+	case character == '{':
+		return p.parseObject()
+	case character == '[':
+		return p.parseArray()
+	case character == '"' || character == '\'':
+		return p.parseString()
+	case character == '-' || character == '+' || character == '.' || ('0' <= character && character <= '9'):
+		return p.parseNumber()
+	case isIdentifierStart(character):
+		start := p.pos
+		identifier, err := p.parseIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		switch identifier {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		case "Infinity":
+			return math.Inf(1), nil
+		case "NaN":
+			return math.NaN(), nil
+		}
+		p.pos = start
+		return nil, p.errorf("invalid literal %q", identifier)
+	case character == -1:
+		return nil, p.errorf("unexpected end of input")
+	default:
+		return nil, p.errorf("invalid character %s looking for beginning of value", p.describe())
+	}
+}
+
+// Moves into an array or object, failing if that nests them too deeply. The caller must call
+// leave once it's done.
+func (p *json5Parser) enter() error {
+	if p.depth >= maxNestingDepth {
+		return p.errorf("exceeded max depth")
+	}
+	p.depth++
+	return nil
+}
+
+// Moves out of an array or object.
+func (p *json5Parser) leave() {
+	p.depth--
+}
+
+// Parses an object, allowing identifier keys and a trailing comma.
+func (p *json5Parser) parseObject() (interface{}, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	object := map[string]interface{}{}
+	p.pos++
+
+	for {
+		if err := p.skipWhitespace(); err != nil {
+			return nil, err
+		}
+
+		character, _ := p.peek()
+		if character == '}' {
+			p.pos++
+			return object, nil
+		}
+
+		var key string
+		var err error
+		keyStart := p.pos
+		switch {
+		case character == '"' || character == '\'':
+			key, err = p.parseString()
+		case isIdentifierStart(character):
+			key, err = p.parseIdentifier()
+		default:
+			err = p.errorf("invalid character %s looking for beginning of object key", p.describe())
+		}
+		if err != nil {
+			return nil, err
+		}
+		if p.keys != nil {
+			p.keys[joinPath(p.path, key)] = int64(keyStart)
+		}
+
+		if err := p.skipWhitespace(); err != nil {
+			return nil, err
+		}
+		if character, _ := p.peek(); character != ':' {
+			return nil, p.errorf("invalid character %s after object key", p.describe())
+		}
+		p.pos++
+
+		if err := p.skipWhitespace(); err != nil {
+			return nil, err
+		}
+		parent := p.path
+		p.path = joinPath(parent, key)
+		value, err := p.parseValue()
+		p.path = parent
+		if err != nil {
+			return nil, err
+		}
+		object[key] = value
+
+		if err := p.skipWhitespace(); err != nil {
+			return nil, err
+		}
+		switch character, _ := p.peek(); character {
+		case ',':
+			p.pos++
+		case '}':
+		default:
+			return nil, p.errorf("invalid character %s after object key:value pair", p.describe())
+		}
+	}
+}
+
+// Parses an array, allowing a trailing comma.
+func (p *json5Parser) parseArray() (interface{}, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	array := []interface{}{}
+	p.pos++
+
+	for {
+		if err := p.skipWhitespace(); err != nil {
+			return nil, err
+		}
+
+		if character, _ := p.peek(); character == ']' {
+			p.pos++
+			return array, nil
+		}
+
+		parent := p.path
+		p.path = joinPath(parent, strconv.Itoa(len(array)))
+		value, err := p.parseValue()
+		p.path = parent
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, value)
+
+		if err := p.skipWhitespace(); err != nil {
+			return nil, err
+		}
+		switch character, _ := p.peek(); character {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, p.errorf("invalid character %s after array element", p.describe())
+		}
+	}
+}
+
+// Parses a single or double quoted string. A backslash before a line terminator continues the
+// string onto the next line.
+func (p *json5Parser) parseString() (string, error) {
+	quote := p.data[p.pos]
+	p.pos++
+
+	var builder strings.Builder
+	for {
+		character, width := p.peek()
+		switch {
+		case character == -1:
+			return "", p.errorf("unterminated string")
+		case character == rune(quote):
+			p.pos++
+			return builder.String(), nil
+		case character == '\n' || character == '\r':
+			return "", p.errorf("invalid line terminator in string")
+		case character == '\\':
+			p.pos++
+			if err := p.parseEscape(&builder); err != nil {
+				return "", err
+			}
+		default:
+			builder.WriteRune(character)
+			p.pos += width
+		}
+	}
+}
+
+// Parses the escape sequence following a backslash, writing the character it represents.
+func (p *json5Parser) parseEscape(builder *strings.Builder) error {
+	character, width := p.peek()
+	p.pos += width
+
+	switch character {
+	case -1:
+		return p.errorf("unterminated string")
+	case 'b':
+		builder.WriteByte('\b')
+	case 'f':
+		builder.WriteByte('\f')
+	case 'n':
+		builder.WriteByte('\n')
+	case 'r':
+		builder.WriteByte('\r')
+	case 't':
+		builder.WriteByte('\t')
+	case 'v':
+		builder.WriteByte('\v')
+	case '0':
+		if next, _ := p.peek(); '0' <= next && next <= '9' {
+			return p.errorf("invalid octal escape in string")
+		}
+		builder.WriteByte(0)
+	case 'x':
+		value, err := p.parseHex(2)
+		if err != nil {
+			return err
+		}
+		builder.WriteRune(value)
+	case 'u':
+		value, err := p.parseHex(4)
+		if err != nil {
+			return err
+		}
+		// Join surrogate pairs back into a single character
+		if utf16IsHighSurrogate(value) && strings.HasPrefix(string(p.data[p.pos:]), "\\u") {
+			start := p.pos
+			p.pos += 2
+			low, err := p.parseHex(4)
+			if err == nil && utf16IsLowSurrogate(low) {
+				value = (value-0xd800)<<10 + (low - 0xdc00) + 0x10000
+			} else {
+				p.pos = start
+			}
+		}
+		builder.WriteRune(value)
+	case '\n', '\u2028', '\u2029':
+	case '\r':
+		if next, _ := p.peek(); next == '\n' {
+			p.pos++
+		}
+	case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		p.pos -= width
+		return p.errorf("invalid escape %s in string", p.describe())
+	default:
+		// Every other character, including quotes and backslashes, escapes to itself
+		builder.WriteRune(character)
+	}
+	return nil
+}
+
+// Parses exactly count hexadecimal digits.
+func (p *json5Parser) parseHex(count int) (rune, error) {
+	if p.pos+count > len(p.data) {
+		return 0, p.errorf("invalid hexadecimal escape in string")
+	}
+	value, err := strconv.ParseUint(string(p.data[p.pos:p.pos+count]), 16, 32)
+	if err != nil {
+		return 0, p.errorf("invalid hexadecimal escape in string")
+	}
+	p.pos += count
+	return rune(value), nil
+}
+
+func utf16IsHighSurrogate(value rune) bool {
+	return 0xd800 <= value && value < 0xdc00
+}
+
+func utf16IsLowSurrogate(value rune) bool {
+	return 0xdc00 <= value && value < 0xe000
+}
+
+// Reports whether the rune can begin an ECMAScript identifier.
+func isIdentifierStart(character rune) bool {
+	return character == '$' || character == '_' || character == '\\' || unicode.IsLetter(character) || unicode.Is(unicode.Nl, character)
+}
+
+// Reports whether the rune can continue an ECMAScript identifier.
+func isIdentifierPart(character rune) bool {
+	return isIdentifierStart(character) || unicode.IsDigit(character) ||
+		unicode.In(character, unicode.Mn, unicode.Mc, unicode.Pc) || character == '\u200c' || character == '\u200d'
+}
+
+// Parses an unquoted identifier such as an object key or one of the literals true, false, null,
+// Infinity and NaN. Identifiers may contain \u escapes.
+func (p *json5Parser) parseIdentifier() (string, error) {
+	var builder strings.Builder
+	for first := true; ; first = false {
+		character, width := p.peek()
+		if character == -1 || !(isIdentifierPart(character) && (!first || isIdentifierStart(character))) {
+			break
+		}
+
+		if character == '\\' {
+			p.pos++
+			if next, _ := p.peek(); next != 'u' {
+				return "", p.errorf("invalid escape in identifier")
+			}
+			p.pos++
+			value, err := p.parseHex(4)
+			if err != nil || value == '\\' || !isIdentifierPart(value) || (first && !isIdentifierStart(value)) {
+				return "", p.errorf("invalid escape in identifier")
+			}
+			builder.WriteRune(value)
+			continue
+		}
+
+		builder.WriteRune(character)
+		p.pos += width
+	}
+	return builder.String(), nil
+}
+
+// Parses a number, which may be signed, hexadecimal, Infinity or NaN, and may start or end with
+// a decimal point.
+func (p *json5Parser) parseNumber() (interface{}, error) {
+	start := p.pos
+	negative := false
+	if character, _ := p.peek(); character == '+' || character == '-' {
+		negative = character == '-'
+		p.pos++
+	}
+
+	if character, _ := p.peek(); isIdentifierStart(character) {
+		identifierStart := p.pos
+		identifier, err := p.parseIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		switch identifier {
+		case "Infinity":
+			if negative {
+				return math.Inf(-1), nil
+			}
+			return math.Inf(1), nil
+		case "NaN":
+			return math.NaN(), nil
+		}
+		p.pos = identifierStart
+		return nil, p.errorf("invalid character %s in numeric literal", p.describe())
+	}
+
+	if p.pos+1 < len(p.data) && p.data[p.pos] == '0' && (p.data[p.pos+1] == 'x' || p.data[p.pos+1] == 'X') {
+		p.pos += 2
+		digitsStart := p.pos
+		for p.pos < len(p.data) && isHexDigit(p.data[p.pos]) {
+			p.pos++
+		}
+		if p.pos == digitsStart {
+			return nil, p.errorf("invalid character %s in hexadecimal literal", p.describe())
+		}
+		return p.finishHexNumber(start, string(p.data[digitsStart:p.pos]), negative)
+	}
+
+	digits := p.skipDigits()
+	if digits > 1 && p.data[p.pos-digits] == '0' {
+		p.pos -= digits - 1
+		return nil, p.errorf("invalid character %s after leading zero", p.describe())
+	}
+
+	if p.pos < len(p.data) && p.data[p.pos] == '.' {
+		p.pos++
+		digits += p.skipDigits()
+	}
+	if digits == 0 {
+		return nil, p.errorf("invalid character %s in numeric literal", p.describe())
+	}
+
+	if p.pos < len(p.data) && (p.data[p.pos] == 'e' || p.data[p.pos] == 'E') {
+		p.pos++
+		if p.pos < len(p.data) && (p.data[p.pos] == '+' || p.data[p.pos] == '-') {
+			p.pos++
+		}
+		if p.skipDigits() == 0 {
+			return nil, p.errorf("invalid character %s in exponent of numeric literal", p.describe())
+		}
+	}
+
+	if character, _ := p.peek(); isIdentifierPart(character) {
+		return nil, p.errorf("invalid character %s in numeric literal", p.describe())
+	}
+
+	return p.finishDecimalNumber(start, string(p.data[start:p.pos]))
+}
+
+// Converts the text of a decimal number into the value handed back to the caller.
+func (p *json5Parser) finishDecimalNumber(start int, text string) (interface{}, error) {
+	if p.preciseNumbers {
+		return json.Number(normaliseJSON5Number(text)), nil
+	}
+
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		p.pos = start
+		return nil, p.errorf("numeric literal %s is out of range", text)
+	}
+	return value, nil
+}
+
+// Converts the digits of a hexadecimal number into the value handed back to the caller.
+func (p *json5Parser) finishHexNumber(start int, digits string, negative bool) (interface{}, error) {
+	value, ok := new(big.Int).SetString(digits, 16)
+	if !ok {
+		p.pos = start
+		return nil, p.errorf("invalid hexadecimal literal 0x%s", digits)
+	}
+	if negative {
+		value.Neg(value)
+	}
+
+	if p.preciseNumbers {
+		return json.Number(value.String()), nil
+	}
+	number, _ := new(big.Float).SetInt(value).Float64()
+	return number, nil
+}
+
+// Rewrites a JSON5 decimal number into the stricter form JSON uses, so that it can be stored in
+// a json.Number. That means no leading + and no decimal point without digits on both sides.
+func normaliseJSON5Number(text string) string {
+	text = strings.TrimPrefix(text, "+")
+	sign := ""
+	if strings.HasPrefix(text, "-") {
+		sign = "-"
+		text = text[1:]
+	}
+	if strings.HasPrefix(text, ".") {
+		text = "0" + text
+	}
+
+	exponent := ""
+	if index := strings.IndexAny(text, "eE"); index >= 0 {
+		text, exponent = text[:index], text[index:]
+	}
+	return sign + strings.TrimSuffix(text, ".") + exponent
+}
+
+// Advances past a run of decimal digits, returning how many there were.
+func (p *json5Parser) skipDigits() int {
+	start := p.pos
+	for p.pos < len(p.data) && '0' <= p.data[p.pos] && p.data[p.pos] <= '9' {
+		p.pos++
+	}
+	return p.pos - start
+}
+
+func isHexDigit(character byte) bool {
+	return ('0' <= character && character <= '9') || ('a' <= character && character <= 'f') || ('A' <= character && character <= 'F')
+}
@@ -0,0 +1,182 @@
+package jsonconfig_test
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/callum-ramage/jsonconfig"
+)
+
+func TestJSON5(test *testing.T) {
+	config, err := jsonconfig.LoadAbstract("./configs/JSON5Config.conf", `{
+		example_default: 4, /* defaults are JSON5 too */
+	}`, jsonconfig.JSON5())
+
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	if config["example_string"].Str != "string value" {
+		fmt.Println(config["example_string"].Str)
+		test.Error()
+	}
+
+	if len(config["example_array"].Arr) != 2 || config["example_array.1"].Str != "array value 1" {
+		fmt.Println(config["example_array"].Arr)
+		test.Error()
+	}
+
+	if config.Get("example_object.example_number").Num != 5.3 {
+		fmt.Println(config.Get("example_object.example_number").Num)
+		test.Error()
+	}
+
+	if config["example_object.example_hex"].Int != 255 {
+		fmt.Println(config["example_object.example_hex"].Int)
+		test.Error()
+	}
+
+	if config["example_object.example_positive"].Num != 0.5 {
+		fmt.Println(config["example_object.example_positive"].Num)
+		test.Error()
+	}
+
+	if config["example_multiline"].Str != "first line second line" {
+		fmt.Println(config["example_multiline"].Str)
+		test.Error()
+	}
+
+	if !math.IsInf(config["example_infinity"].Num, -1) || !math.IsNaN(config["example_nan"].Num) {
+		fmt.Println(config["example_infinity"].Num, config["example_nan"].Num)
+		test.Error()
+	}
+
+	if config["$example_dollar_key"].Str != `it's "quoted"` {
+		fmt.Println(config["$example_dollar_key"].Str)
+		test.Error()
+	}
+
+	if config["example_default"].Int != 4 {
+		fmt.Println(config["example_default"].Int)
+		test.Error()
+	}
+}
+
+func TestJSON5Strings(test *testing.T) {
+	config, err := jsonconfig.LoadString(`{
+		escapes: '\x41é😀\v\0\q\/',
+		crlf: 'a\`+"\r\n"+`b',
+		"unicode_key_a": 1,
+	}`, "", jsonconfig.JSON5())
+
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	if config["escapes"].Str != "Aé😀\v\x00q/" {
+		fmt.Printf("%q\n", config["escapes"].Str)
+		test.Error()
+	}
+
+	if config["crlf"].Str != "ab" {
+		fmt.Printf("%q\n", config["crlf"].Str)
+		test.Error()
+	}
+
+	if config["unicode_key_a"].Int != 1 {
+		fmt.Println(config["unicode_key_a"].Int)
+		test.Error()
+	}
+}
+
+func TestJSON5Invalid(test *testing.T) {
+	invalid := []string{
+		`{a: 1`,
+		`{a: 01}`,
+		`{a: 'unterminated}`,
+		`{a: "line
+break"}`,
+		`{a: [1,,2]}`,
+		`{a: undefined}`,
+		`{a: 1} trailing`,
+		`{a: 0x}`,
+		`{a: 1 /* unterminated`,
+		`{1a: 1}`,
+	}
+
+	for _, source := range invalid {
+		if _, err := jsonconfig.LoadString(source, "", jsonconfig.JSON5()); err == nil {
+			fmt.Println(source)
+			test.Error()
+		}
+	}
+}
+
+func TestJSON5HashComments(test *testing.T) {
+	source := "{\n  # a comment\n  a: '# not a comment', # another\n}"
+	config, err := jsonconfig.LoadString(source, "", jsonconfig.JSON5(), jsonconfig.HashComments())
+	if err != nil || config["a"].Str != "# not a comment" {
+		fmt.Println(err, config)
+		test.Error()
+	}
+
+	// Without HashComments a # is still an error
+	if _, err := jsonconfig.LoadString(source, "", jsonconfig.JSON5()); err == nil {
+		test.Error()
+	}
+}
+
+func TestJSON5NestingLimit(test *testing.T) {
+	deep := "{a: " + strings.Repeat("[", 100000) + strings.Repeat("]", 100000) + "}"
+	_, err := jsonconfig.LoadString(deep, "", jsonconfig.JSON5())
+	var parseErr *jsonconfig.ParseError
+	if !errors.As(err, &parseErr) || !strings.Contains(err.Error(), "exceeded max depth") {
+		fmt.Println(err)
+		test.Error()
+	}
+
+	// Nesting up to the limit is fine
+	nested := "{a: " + strings.Repeat("[", 9999) + strings.Repeat("]", 9999) + "}"
+	if _, err := jsonconfig.LoadString(nested, "", jsonconfig.JSON5()); err != nil {
+		fmt.Println(err)
+		test.Error()
+	}
+}
+
+func TestJSON5Load(test *testing.T) {
+	config := struct {
+		Example_string string
+		Example_array  []string
+		Example_object struct {
+			Example_hex int
+		}
+	}{}
+
+	err := jsonconfig.Load("./configs/ExampleConfig.conf", &config, jsonconfig.JSON5())
+	if err != nil || config.Example_string != "string value" {
+		fmt.Println(err, config.Example_string)
+		test.Error()
+	}
+
+	// NaN and Infinity can't be put in a struct via JSON
+	err = jsonconfig.Load("./configs/JSON5Config.conf", &config, jsonconfig.JSON5())
+	if err == nil {
+		test.Error()
+	}
+	// Large integers keep their precision
+	precise := struct {
+		Example_id int64
+	}{}
+	err = jsonconfig.Load("./configs/PreciseNumberConfig.conf", &precise, jsonconfig.JSON5())
+	if err != nil || precise.Example_id != 9007199254740993 {
+		fmt.Println(err, precise.Example_id)
+		test.Error()
+	}
+}
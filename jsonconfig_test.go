@@ -1,7 +1,10 @@
 package jsonconfig_test
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/callum-ramage/jsonconfig"
@@ -25,7 +28,7 @@ func TestLoadAbstractNoCollapse(test *testing.T) {
 		test.Error()
 	}
 
-	if config.Get("test_array.0").Str == "array value 0" {
+	if config.Get("test_array.0").Str != "array value 0" {
 		fmt.Println(config.Get("test_array.0").Str)
 		test.Error()
 	}
@@ -161,6 +164,42 @@ func TestLoadAbstract(test *testing.T) {
 	}
 }
 
+func TestLoadNilTarget(test *testing.T) {
+	var invalid *json.InvalidUnmarshalError
+	if err := jsonconfig.Load("./configs/ExampleConfig.conf", nil); !errors.As(err, &invalid) {
+		fmt.Println(err)
+		test.Error()
+	}
+	if err := jsonconfig.Load("./configs/ExampleConfig.conf", nil, jsonconfig.Strict()); !errors.As(err, &invalid) {
+		fmt.Println(err)
+		test.Error()
+	}
+}
+
+func TestCollapseCopies(test *testing.T) {
+	config, _ := jsonconfig.LoadAbstract("./configs/ExampleConfig.conf", "")
+
+	// The values added are plain copies of the values they name
+	if !reflect.DeepEqual(config["example_object.example_number"], config["example_object"].Obj["example_number"]) {
+		fmt.Println(config["example_object.example_number"])
+		test.Error()
+	}
+
+	// JSONValue can still be written without field names
+	value := jsonconfig.JSONValue{"string value", nil, "string value", 0, 0, false, nil}
+	if !reflect.DeepEqual(value.Value, config["example_string"].Value) {
+		test.Error()
+	}
+
+	// Dotted keys from the file are kept apart from the ones Collapse adds, even with the same value
+	config, _ = jsonconfig.LoadString(`{"a": {"b": 1}, "a.b": 1}`, "")
+	config.Collapse()
+	if encoded, _ := config.MarshalJSON(); string(encoded) != `{"a":{"b":1},"a.b":1}` {
+		fmt.Println(string(encoded))
+		test.Error()
+	}
+}
+
 func TestLoadString(test *testing.T) {
 	config, err := jsonconfig.LoadString(`
 	{
@@ -213,7 +252,7 @@ func TestLoadString(test *testing.T) {
 		test.Error()
 	}
 
-	if config.Get("test_array.0").Str == "array value 0" {
+	if config.Get("test_array.0").Str != "array value 0" {
 		fmt.Println(config.Get("test_array.0").Str)
 		test.Error()
 	}
@@ -522,3 +561,80 @@ func ExampleLoad() {
 	// example_object: 5.3
 	// example_default: 4
 }
+
+func TestPreciseNumbers(test *testing.T) {
+	config, err := jsonconfig.LoadAbstract("./configs/PreciseNumberConfig.conf", `{"example_default": 4}`, jsonconfig.PreciseNumbers())
+
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	if config["example_id"].Int64() != 9007199254740993 || config["example_id"].Uint64() != 9007199254740993 {
+		fmt.Println(config["example_id"].Int64(), config["example_id"].Uint64())
+		test.Error()
+	}
+
+	if config["example_max"].Uint64() != 18446744073709551615 || config["example_max"].Int64() != 0 {
+		fmt.Println(config["example_max"].Uint64(), config["example_max"].Int64())
+		test.Error()
+	}
+
+	if config["example_negative"].Int64() != -9223372036854775808 || config["example_negative"].Uint64() != 0 {
+		fmt.Println(config["example_negative"].Int64(), config["example_negative"].Uint64())
+		test.Error()
+	}
+
+	// The old fields are still filled in
+	if config["example_number"].Num != 5.3 || config["example_number"].Int != 5 || config["example_size"].Int != 1000 {
+		fmt.Println(config["example_number"].Num, config["example_number"].Int, config["example_size"].Int)
+		test.Error()
+	}
+
+	if config["example_number"].Decimal().String() != "53/10" || config["example_number"].Int64() != 5 {
+		fmt.Println(config["example_number"].Decimal(), config["example_number"].Int64())
+		test.Error()
+	}
+
+	if config["example_default"].Int64() != 4 {
+		fmt.Println(config["example_default"].Int64())
+		test.Error()
+	}
+
+	// Without the option the id has already been rounded by float64
+	config, _ = jsonconfig.LoadAbstract("./configs/PreciseNumberConfig.conf", "")
+	if config["example_id"].Int64() != 9007199254740992 {
+		fmt.Println(config["example_id"].Int64())
+		test.Error()
+	}
+
+	if jsonconfig.NewJSONValue("5").Decimal() != nil || jsonconfig.NewJSONValue("5").Int64() != 0 {
+		test.Error()
+	}
+}
+
+func TestPreciseNumbersJSON5(test *testing.T) {
+	config, err := jsonconfig.LoadString(`{id: 0x20000000000001, a: +.5, b: 5., c: -5.e1, d: Infinity}`, "", jsonconfig.JSON5(), jsonconfig.PreciseNumbers())
+
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	if config["id"].Int64() != 9007199254740993 {
+		fmt.Println(config["id"].Int64())
+		test.Error()
+	}
+
+	if config["a"].Value != json.Number("0.5") || config["b"].Value != json.Number("5") || config["c"].Value != json.Number("-5e1") {
+		fmt.Println(config["a"].Value, config["b"].Value, config["c"].Value)
+		test.Error()
+	}
+
+	if config["d"].Decimal() != nil || config["c"].Int != -50 {
+		fmt.Println(config["d"].Decimal(), config["c"].Int)
+		test.Error()
+	}
+}
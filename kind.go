@@ -0,0 +1,115 @@
+package jsonconfig
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// Identifies which of the JSON types a JSONValue holds.
+type Kind int
+
+const (
+	KindNull Kind = iota
+	KindBool
+	KindNumber
+	KindString
+	KindArray
+	KindObject
+)
+
+func (kind Kind) String() string {
+	switch kind {
+	case KindBool:
+		return "bool"
+	case KindNumber:
+		return "number"
+	case KindString:
+		return "string"
+	case KindArray:
+		return "array"
+	case KindObject:
+		return "object"
+	default:
+		return "null"
+	}
+}
+
+// Returns which of the JSON types the value holds. Anything that isn't one of the types produced
+// by decoding JSON is reported as KindNull.
+func (key JSONValue) Kind() Kind {
+	switch key.Value.(type) {
+	case bool:
+		return KindBool
+	case float64, int, json.Number:
+		return KindNumber
+	case string:
+		return KindString
+	case []interface{}:
+		return KindArray
+	case map[string]interface{}:
+		return KindObject
+	default:
+		return KindNull
+	}
+}
+
+// Returns the value as a string, and whether it actually was a string.
+func (key JSONValue) AsString() (string, bool) {
+	value, ok := key.Value.(string)
+	return value, ok
+}
+
+// Returns the value as a bool, and whether it actually was a bool.
+func (key JSONValue) AsBoolean() (bool, bool) {
+	value, ok := key.Value.(bool)
+	return value, ok
+}
+
+// Returns the value as a float64, and whether it actually was a number.
+func (key JSONValue) AsNumber() (float64, bool) {
+	return key.Number(), key.Kind() == KindNumber
+}
+
+// Returns the value as an int, and whether it was a whole number small enough to fit in an int.
+// So 5.3 or "5" will report false rather than quietly becoming 5 and 0.
+func (key JSONValue) AsInteger() (int, bool) {
+	integer, ok := key.AsInt64()
+	if !ok || integer < math.MinInt || integer > math.MaxInt {
+		return 0, false
+	}
+	return int(integer), true
+}
+
+// Returns the value as an int64, and whether it was a whole number that fits in an int64.
+func (key JSONValue) AsInt64() (int64, bool) {
+	decimal := key.Decimal()
+	if decimal == nil || !decimal.IsInt() || !decimal.Num().IsInt64() {
+		return 0, false
+	}
+	return decimal.Num().Int64(), true
+}
+
+// Returns the value as a uint64, and whether it was a whole number that fits in a uint64.
+func (key JSONValue) AsUint64() (uint64, bool) {
+	decimal := key.Decimal()
+	if decimal == nil || !decimal.IsInt() || !decimal.Num().IsUint64() {
+		return 0, false
+	}
+	return decimal.Num().Uint64(), true
+}
+
+// Returns the value's elements, and whether it actually was an array.
+func (key JSONValue) AsArray() ([]JSONValue, bool) {
+	if key.Kind() != KindArray {
+		return nil, false
+	}
+	return key.Arr, true
+}
+
+// Returns the value's members, and whether it actually was an object.
+func (key JSONValue) AsObject() (Configuration, bool) {
+	if key.Kind() != KindObject {
+		return nil, false
+	}
+	return key.Obj, true
+}
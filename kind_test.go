@@ -0,0 +1,112 @@
+package jsonconfig_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/callum-ramage/jsonconfig"
+)
+
+func TestKind(test *testing.T) {
+	config, err := jsonconfig.LoadString(`{
+		"null": null,
+		"bool": false,
+		"number": 8080,
+		"string": "8080",
+		"array": [],
+		"object": {}
+	}`, "")
+
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	kinds := map[string]jsonconfig.Kind{
+		"null":    jsonconfig.KindNull,
+		"bool":    jsonconfig.KindBool,
+		"number":  jsonconfig.KindNumber,
+		"string":  jsonconfig.KindString,
+		"array":   jsonconfig.KindArray,
+		"object":  jsonconfig.KindObject,
+		"missing": jsonconfig.KindNull,
+	}
+	for key, kind := range kinds {
+		if config[key].Kind() != kind || config[key].Kind().String() != key && key != "missing" {
+			fmt.Println(key, config[key].Kind())
+			test.Error()
+		}
+	}
+}
+
+func TestTypedAccessors(test *testing.T) {
+	config, err := jsonconfig.LoadString(`{
+		"port": 8080,
+		"quoted_port": "8080",
+		"ratio": 5.3,
+		"huge": 1e300,
+		"enabled": true,
+		"hosts": ["a"],
+		"object": {"a": 1}
+	}`, "", jsonconfig.PreciseNumbers())
+
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	if port, ok := config["port"].AsInteger(); !ok || port != 8080 {
+		fmt.Println(port, ok)
+		test.Error()
+	}
+
+	if port, ok := config["quoted_port"].AsInteger(); ok || port != 0 {
+		fmt.Println(port, ok)
+		test.Error()
+	}
+
+	if port, ok := config["quoted_port"].AsString(); !ok || port != "8080" {
+		fmt.Println(port, ok)
+		test.Error()
+	}
+
+	if ratio, ok := config["ratio"].AsInteger(); ok {
+		fmt.Println(ratio, ok)
+		test.Error()
+	}
+
+	if ratio, ok := config["ratio"].AsNumber(); !ok || ratio != 5.3 {
+		fmt.Println(ratio, ok)
+		test.Error()
+	}
+
+	if _, ok := config["huge"].AsInt64(); ok {
+		test.Error()
+	}
+
+	if _, ok := config["port"].AsBoolean(); ok {
+		test.Error()
+	}
+
+	if enabled, ok := config["enabled"].AsBoolean(); !ok || !enabled {
+		test.Error()
+	}
+
+	if hosts, ok := config["hosts"].AsArray(); !ok || len(hosts) != 1 {
+		test.Error()
+	}
+
+	if _, ok := config["hosts"].AsObject(); ok {
+		test.Error()
+	}
+
+	if object, ok := config["object"].AsObject(); !ok || object["a"].Int != 1 {
+		test.Error()
+	}
+
+	if value, ok := config["missing"].AsUint64(); ok || value != 0 {
+		test.Error()
+	}
+}
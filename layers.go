@@ -0,0 +1,92 @@
+package jsonconfig
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strconv"
+)
+
+// Records which file each value of a layered configuration came from, keyed by dotted path. Keys
+// containing dots are escaped within the paths, as they would be for Get.
+// Objects are merged between layers, so an object is attributed to the file that first set it
+// while its members are attributed to the files that last set them. Arrays are replaced whole,
+// so everything within an array is attributed to the file the array came from.
+type Sources map[string]string
+
+// Returns the file that the value at the dotted path came from, or "" if it came from no file.
+func (sources Sources) Of(path string) string {
+	return sources[path]
+}
+
+// Loads each file in turn, with values in later files overriding those in earlier ones, so
+// that a base configuration can be refined by environment and local files.
+//
+//	config, sources, err := jsonconfig.LoadLayers("base.conf", "production.conf", "local.conf")
+//
+// Objects found in more than one file are merged, while arrays and other values are replaced
+// whole. Use LoadLayersWithOptions and LayerStrategy to combine them differently. The
+// configuration is collapsed in the same way as LoadAbstract, and sources records which file
+// each value came from.
+func LoadLayers(paths ...string) (config Configuration, sources Sources, err error) {
+	return LoadLayersWithOptions(paths)
+}
+
+// Does the same as LoadLayers, applying the options to every file.
+func LoadLayersWithOptions(paths []string, options ...LoadOption) (config Configuration, sources Sources, err error) {
+	combinedOptions := newLoadOptions(options)
+	config = Configuration{}
+	sources = Sources{}
+
+	for _, path := range paths {
+		if combinedOptions.skipMissingFiles {
+			if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+		}
+
+		layer, err := loadFileAsJSON(path, combinedOptions, nil)
+		if err != nil {
+			return Configuration{}, nil, err
+		}
+		path := path
+		layerMerger := merger{
+			strategy: combinedOptions.layerStrategy,
+			copied: func(valuePath string, old JSONValue, value JSONValue) {
+				sources.forget(valuePath, old)
+				sources.record(valuePath, value, path)
+			},
+			deleted: sources.forget,
+		}
+		layerMerger.strategy.Override = true
+		layerMerger.mergeMembers(config, nil, layer, "")
+	}
+
+	if err = combinedOptions.finishAbstract(config); err != nil {
+		return Configuration{}, nil, err
+	}
+	config.Collapse()
+	return config, sources, nil
+}
+
+// Records source as the source of the value at path and of every value within it.
+func (sources Sources) record(path string, value JSONValue, source string) {
+	sources[path] = source
+	for key, member := range value.Obj {
+		sources.record(joinPath(path, key), member, source)
+	}
+	for i, element := range value.Arr {
+		sources.record(joinPath(path, strconv.Itoa(i)), element, source)
+	}
+}
+
+// Removes the record of old, the value that was at path, and of every value within it.
+func (sources Sources) forget(path string, old JSONValue) {
+	delete(sources, path)
+	for key, member := range old.Obj {
+		sources.forget(joinPath(path, key), member)
+	}
+	for i, element := range old.Arr {
+		sources.forget(joinPath(path, strconv.Itoa(i)), element)
+	}
+}
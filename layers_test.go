@@ -0,0 +1,131 @@
+package jsonconfig_test
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/callum-ramage/jsonconfig"
+)
+
+func TestLoadLayers(test *testing.T) {
+	config, sources, err := jsonconfig.LoadLayers("./configs/layers/Base.conf", "./configs/layers/Production.conf")
+
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	expected := map[string]interface{}{
+		"name":                   "service",
+		"server.host":            "0.0.0.0",
+		"server.port":            float64(443),
+		"server.tls.enabled":     true,
+		"server.tls.certificate": "/etc/tls/cert.pem",
+		"upstreams.0":            "c.internal",
+		"debug":                  false,
+	}
+	for path, value := range expected {
+		if config[path].Value != value {
+			fmt.Println(path, config[path].Value)
+			test.Error()
+		}
+	}
+
+	if len(config["upstreams"].Arr) != 1 {
+		fmt.Println(config["upstreams"].Arr)
+		test.Error()
+	}
+
+	expectedSources := map[string]string{
+		"name":               "./configs/layers/Base.conf",
+		"server.host":        "./configs/layers/Base.conf",
+		"server.port":        "./configs/layers/Production.conf",
+		"server.tls.enabled": "./configs/layers/Production.conf",
+		"upstreams":          "./configs/layers/Production.conf",
+		"upstreams.0":        "./configs/layers/Production.conf",
+		"missing":            "",
+	}
+	for path, source := range expectedSources {
+		if sources.Of(path) != source {
+			fmt.Println(path, sources.Of(path))
+			test.Error()
+		}
+	}
+}
+
+func TestLoadLayersReplacingObjects(test *testing.T) {
+	config, sources, err := jsonconfig.LoadLayersWithOptions([]string{
+		"./configs/layers/Base.conf",
+		"./configs/layers/Production.conf",
+		"./configs/layers/Missing.conf",
+		"./configs/layers/Local.conf",
+	}, jsonconfig.SkipMissingFiles())
+
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	if config["server.tls"].Value != false || !config["debug"].Bool || config["server.port"].Int != 443 {
+		fmt.Println(config["server.tls"].Value, config["debug"].Bool, config["server.port"].Int)
+		test.Error()
+	}
+
+	if _, exists := sources["server.tls.enabled"]; exists || sources["server.tls"] != "./configs/layers/Local.conf" {
+		fmt.Println(sources)
+		test.Error()
+	}
+
+	_, _, err = jsonconfig.LoadLayers("./configs/layers/Base.conf", "./configs/layers/Missing.conf")
+	if !errors.Is(err, fs.ErrNotExist) {
+		fmt.Println(err)
+		test.Error()
+	}
+}
+
+func TestLoadLayersEmptyObjects(test *testing.T) {
+	directory := test.TempDir()
+	base := filepath.Join(directory, "base.conf")
+	local := filepath.Join(directory, "local.conf")
+	os.WriteFile(base, []byte(`{"name": "service", "limits": {}}`), 0o644)
+	os.WriteFile(local, []byte(`{"features": {}, "limits": {"rate": 5}}`), 0o644)
+
+	_, sources, err := jsonconfig.LoadLayers(base, local)
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	if sources.Of("features") != local || sources.Of("limits") != base || sources.Of("limits.rate") != local || sources.Of("name") != base {
+		fmt.Println(sources)
+		test.Error()
+	}
+}
+
+func TestLoadLayersSourcesOfDottedKeys(test *testing.T) {
+	directory := test.TempDir()
+	base := filepath.Join(directory, "base.conf")
+	local := filepath.Join(directory, "local.conf")
+	os.WriteFile(base, []byte(`{"hosts": {"api.internal": {"port": 80}, "api": {"internal": 1}}}`), 0o644)
+	os.WriteFile(local, []byte(`{"hosts": {"api.internal": {"port": 81}}}`), 0o644)
+
+	config, sources, err := jsonconfig.LoadLayers(base, local)
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	path := "hosts." + jsonconfig.EscapeKey("api.internal") + ".port"
+	if sources.Of(path) != local || sources.Of("hosts.api.internal") != base || config.Get(path).Value != float64(81) {
+		fmt.Println(sources)
+		test.Error()
+	}
+}
@@ -0,0 +1,202 @@
+package jsonconfig
+
+import (
+	"os"
+	"reflect"
+)
+
+// Alters how the Load family of funcs read and parse a configuration.
+type LoadOption func(*loadOptions)
+
+// The accumulated effect of every LoadOption handed to a Load func.
+type loadOptions struct {
+	stripperOptions  []StripperOption
+	json5            bool
+	preciseNumbers   bool
+	lookupEnv        LookupFunc
+	envPrefix        string
+	lookupOverride   LookupFunc
+	maxIncludeDepth  int
+	skipMissingFiles bool
+	layerStrategy    MergeStrategy
+	schema           *Schema
+	strict           bool
+	// The defaults a strict abstract load checks keys against
+	known map[string]interface{}
+}
+
+// Reports whether the abstract values need altering after they've been parsed, which means
+// they can't be decoded straight into a struct.
+func (options *loadOptions) transformsValues() bool {
+	return options.lookupEnv != nil
+}
+
+// Reports whether # begins a comment. The options only say so by way of a comment stripper.
+func (options *loadOptions) hashComments() bool {
+	stripper := JsonCommentStripper{}
+	for _, option := range options.stripperOptions {
+		option(&stripper)
+	}
+	return stripper.hashComments
+}
+
+// Makes the final adjustments to an abstract configuration once any defaults have been merged in.
+func (options *loadOptions) finishAbstract(config Configuration) error {
+	if options.lookupOverride != nil {
+		err := overrideMembersFromEnv(config, "", options.envPrefix, options.lookupOverride, func(key string, value JSONValue) {
+			config[key] = value
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if options.schema != nil {
+		return options.schema.Validate(config)
+	}
+	return nil
+}
+
+// Makes the final adjustments to a struct once it has been decoded. tree holds the abstract
+// values the struct was decoded from, and is only needed when the struct has jsonconfig tags.
+func (options *loadOptions) finishStruct(target interface{}, tree map[string]interface{}) error {
+	if tree != nil {
+		if err := applyTagDefaults(target, tree); err != nil {
+			return err
+		}
+	}
+	if options.lookupOverride != nil {
+		if err := overrideChildrenFromEnv(reflect.ValueOf(target), "", options.envPrefix, options.lookupOverride); err != nil {
+			return err
+		}
+	}
+	if tree != nil {
+		return checkFieldRules(target, tree)
+	}
+	return nil
+}
+
+// Applies each of the options in order over the default load behaviour.
+func newLoadOptions(options []LoadOption) *loadOptions {
+	combined := &loadOptions{maxIncludeDepth: defaultMaxIncludeDepth}
+	for _, option := range options {
+		option(combined)
+	}
+	return combined
+}
+
+// Allows # to begin a comment that runs to the end of the line, in the same way as //.
+// As with other comments, a # within a string is left alone. This works with JSON5 too.
+func HashComments() LoadOption {
+	return func(options *loadOptions) {
+		options.stripperOptions = append(options.stripperOptions, StripHashComments())
+	}
+}
+
+// Parses configurations as JSON5 (https://spec.json5.org) rather than JSON. JSON5 allows
+// comments, trailing commas, unquoted keys, single quoted and multi-line strings, hexadecimal
+// numbers, Infinity and NaN. Infinity and NaN can't be represented in plain JSON, so they can
+// only be loaded with LoadAbstract and friends and not into a struct with Load.
+func JSON5() LoadOption {
+	return func(options *loadOptions) {
+		options.json5 = true
+	}
+}
+
+// Keeps numbers as json.Number rather than converting them to float64, so that large integers
+// such as 64-bit IDs don't lose precision. Num and Int are still filled in as normal, while the
+// exact value is available from Int64, Uint64 and Decimal.
+func PreciseNumbers() LoadOption {
+	return func(options *loadOptions) {
+		options.preciseNumbers = true
+	}
+}
+
+// Expands references to environment variables within string values, such as
+// "${DB_HOST:-localhost}". Variables are looked up with lookup, or with os.LookupEnv if lookup
+// is nil. The forms understood are
+//
+//	${NAME}           the value of NAME, or nothing if it isn't set
+//	${NAME:-default}  default if NAME isn't set or is empty
+//	${NAME-default}   default if NAME isn't set
+//	${NAME:?message}  an error containing message if NAME isn't set or is empty
+//	${NAME?message}   an error containing message if NAME isn't set
+//	$$                a literal $
+//
+// Defaults may themselves contain references. Keys are never expanded.
+func ExpandEnv(lookup LookupFunc) LoadOption {
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	return func(options *loadOptions) {
+		options.lookupEnv = lookup
+	}
+}
+
+// Overrides values in the loaded configuration with environment variables named after their
+// dotted paths. A name is made from prefix followed by each part of the path in upper case,
+// separated by a double underscore, with anything other than a letter or digit becoming an
+// underscore. So with the prefix "APP_"
+//
+//	example_object.example_number  is overridden by  APP_EXAMPLE_OBJECT__EXAMPLE_NUMBER
+//	example_array.0                is overridden by  APP_EXAMPLE_ARRAY__0
+//
+// Only values that already exist, whether from the file, the defaults or the struct handed to
+// Load, can be overridden, and the variable is converted to the type of the existing value.
+// Whole arrays and objects can be replaced by setting their variable to JSON. Variables are
+// looked up with lookup, or with os.LookupEnv if lookup is nil.
+func EnvOverrides(prefix string, lookup LookupFunc) LoadOption {
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	return func(options *loadOptions) {
+		options.envPrefix = prefix
+		options.lookupOverride = lookup
+	}
+}
+
+// Limits how deeply $include directives can be nested, which is 16 by default. A depth of 0
+// rejects any $include.
+func MaxIncludeDepth(depth int) LoadOption {
+	return func(options *loadOptions) {
+		options.maxIncludeDepth = depth
+	}
+}
+
+// Makes LoadLayersWithOptions skip any files that don't exist, so that optional layers such as
+// local overrides can always be listed.
+func SkipMissingFiles() LoadOption {
+	return func(options *loadOptions) {
+		options.skipMissingFiles = true
+	}
+}
+
+// Sets how LoadLayersWithOptions merges each file over the ones before it, such as appending
+// arrays or letting a null delete a key. Override is ignored since later files always win.
+func LayerStrategy(strategy MergeStrategy) LoadOption {
+	return func(options *loadOptions) {
+		options.layerStrategy = strategy
+	}
+}
+
+// Checks the loaded configuration against the schema, failing the load with ValidationErrors
+// listing every violation. An abstract configuration is checked once any defaults, includes and
+// overrides have been applied, while Load checks the contents of the file before it is decoded
+// into the struct.
+func ValidateSchema(schema *Schema) LoadOption {
+	return func(options *loadOptions) {
+		options.schema = schema
+	}
+}
+
+// Rejects keys that aren't expected, which usually means they've been misspelt. Load rejects
+// keys that don't match a field of the struct, in the same places encoding/json would ignore
+// them, while maps and interface{} fields accept anything. LoadAbstract, LoadAbstractNoCollapse
+// and LoadString reject keys that aren't in the defaults document, including the keys of files
+// pulled in with $include. An empty object in the defaults accepts any keys, as does an empty
+// defaults document, and arrays aren't checked. Every unknown key is reported together as
+// UnknownKeyErrors, giving the line and column of each.
+func Strict() LoadOption {
+	return func(options *loadOptions) {
+		options.strict = true
+	}
+}
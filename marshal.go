@@ -0,0 +1,53 @@
+package jsonconfig
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Controls how Marshal lays out a configuration. The zero value writes compact JSON.
+type MarshalOptions struct {
+	// Begins every line after the first, as with json.MarshalIndent.
+	Prefix string
+	// Indents each level of nesting. Leave empty for compact JSON.
+	Indent string
+	// Escapes <, > and & within strings so that the JSON can be embedded in HTML.
+	EscapeHTML bool
+}
+
+// Encodes the configuration as compact JSON with the same nested structure as the file it was
+// loaded from, including anything merged into it. The dotted keys added by Collapse are left out.
+// A Configuration is a map and doesn't remember the order of the file, so keys are always
+// written in sorted order, which keeps the output stable. Use Document to keep the order of a file.
+func (config Configuration) MarshalJSON() ([]byte, error) {
+	return config.Marshal(MarshalOptions{})
+}
+
+// Encodes the configuration as JSON in the same way as MarshalJSON, laid out as the options say.
+//
+//	effective, err := config.Marshal(jsonconfig.MarshalOptions{Indent: "  "})
+func (config Configuration) Marshal(options MarshalOptions) ([]byte, error) {
+	return options.marshal(config.raw())
+}
+
+// Encodes the value as compact JSON, in the same way as Configuration.MarshalJSON.
+func (key JSONValue) MarshalJSON() ([]byte, error) {
+	return key.Marshal(MarshalOptions{})
+}
+
+// Encodes the value as JSON in the same way as Configuration.MarshalJSON, laid out as the options say.
+func (key JSONValue) Marshal(options MarshalOptions) ([]byte, error) {
+	return options.marshal(key.raw())
+}
+
+// Encodes an abstract value. Infinity and NaN, which JSON5 allows, can't be encoded.
+func (options MarshalOptions) marshal(value interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(options.EscapeHTML)
+	encoder.SetIndent(options.Prefix, options.Indent)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), nil
+}
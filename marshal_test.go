@@ -0,0 +1,108 @@
+package jsonconfig_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/callum-ramage/jsonconfig"
+)
+
+func TestMarshalConfiguration(test *testing.T) {
+	config, err := jsonconfig.LoadAbstract("./configs/ExampleConfig.conf", `{"example_object": {"example_default": "<none>"}}`)
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	encoded, err := config.MarshalJSON()
+	expected := `{"example_array":["array value 0"],"example_object":{"example_default":"<none>","example_number":5.3},"example_string":"string value"}`
+	if err != nil || string(encoded) != expected {
+		fmt.Println(string(encoded), err)
+		test.Error()
+	}
+
+	indented, err := config.Marshal(jsonconfig.MarshalOptions{Indent: "  "})
+	expected = `{
+  "example_array": [
+    "array value 0"
+  ],
+  "example_object": {
+    "example_default": "<none>",
+    "example_number": 5.3
+  },
+  "example_string": "string value"
+}`
+	if err != nil || string(indented) != expected {
+		fmt.Println(string(indented), err)
+		test.Error()
+	}
+}
+
+func TestMarshalJSONValue(test *testing.T) {
+	config, _ := jsonconfig.LoadAbstract("./configs/ExampleConfig.conf", "")
+
+	encoded, err := json.Marshal(config["example_object"])
+	if err != nil || string(encoded) != `{"example_number":5.3}` {
+		fmt.Println(string(encoded), err)
+		test.Error()
+	}
+
+	encoded, err = json.Marshal(struct {
+		Missing jsonconfig.JSONValue
+		Number  jsonconfig.JSONValue
+	}{config["missing"], config["example_object.example_number"]})
+	if err != nil || string(encoded) != `{"Missing":null,"Number":5.3}` {
+		fmt.Println(string(encoded), err)
+		test.Error()
+	}
+}
+
+func TestMarshalAfterChanges(test *testing.T) {
+	config, _ := jsonconfig.LoadAbstract("./configs/ExampleConfig.conf", "")
+	assignments, _ := jsonconfig.ParseAssignments([]string{"example_object.example_number=7", "example_array.0=changed"})
+	if err := assignments.Apply(config); err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	encoded, err := config.Marshal(jsonconfig.MarshalOptions{})
+	expected := `{"example_array":["changed"],"example_object":{"example_number":7},"example_string":"string value"}`
+	if err != nil || string(encoded) != expected {
+		fmt.Println(string(encoded), err)
+		test.Error()
+	}
+
+	// The encoded configuration loads back to the same thing
+	reloaded, err := jsonconfig.LoadString(string(encoded), "")
+	if err != nil || len(jsonconfig.Diff(config, reloaded)) != 0 {
+		fmt.Println(jsonconfig.Diff(config, reloaded), err)
+		test.Error()
+	}
+}
+
+func TestMarshalNaN(test *testing.T) {
+	config, _ := jsonconfig.LoadString(`{"value": NaN}`, "", jsonconfig.JSON5())
+	if _, err := json.Marshal(config); err == nil {
+		test.Error()
+	}
+}
+
+func ExampleConfiguration_Marshal() {
+	config, _ := jsonconfig.LoadAbstract("./configs/ExampleConfig.conf", `{"example_object": {"example_default": true}}`)
+	effective, _ := config.Marshal(jsonconfig.MarshalOptions{Indent: "  "})
+	fmt.Println(string(effective))
+	// Output:
+	// {
+	//   "example_array": [
+	//     "array value 0"
+	//   ],
+	//   "example_object": {
+	//     "example_default": true,
+	//     "example_number": 5.3
+	//   },
+	//   "example_string": "string value"
+	// }
+}
@@ -0,0 +1,232 @@
+package jsonconfig
+
+import (
+	"reflect"
+)
+
+// Decides how arrays found in both configurations are combined by Merge.
+type ArrayMerge int
+
+const (
+	// Uses whichever array wins according to MergeStrategy.Override.
+	ReplaceArrays ArrayMerge = iota
+	// Puts the elements of the other array after those of the calling config's array.
+	AppendArrays
+	// Appends the elements of the other array that aren't already in the calling config's array.
+	AppendUniqueArrays
+	// Merges elements at the same index, using the same strategy, and appends any extra elements.
+	MergeArraysByIndex
+	// Merges objects within the arrays whose KeyField members are equal, using the same strategy,
+	// and appends the elements that don't match.
+	MergeArraysByKey
+)
+
+// Describes how Merge resolves a key found in both configurations.
+type MergeStrategy struct {
+	// If true values in the other config win, otherwise values in the calling config win.
+	Override bool
+	// How arrays found in both configurations are combined.
+	Arrays ArrayMerge
+	// The member used to match objects within arrays when Arrays is MergeArraysByKey.
+	KeyField string
+	// If true a null on the winning side of a collision deletes the key rather than being kept as
+	// a value. A null in the other config when Override is set is never copied across, so a later
+	// layer can remove a key set by an earlier one.
+	NullDeletes bool
+}
+
+// Copies the other Configurations values into the calling config according to the strategy. Objects
+// found in both are always merged, treating them as configs in their own right. The zero strategy
+// behaves the same as MergeConfig.
+//
+//	base.Merge(override, jsonconfig.MergeStrategy{Override: true, Arrays: jsonconfig.AppendUniqueArrays})
+//
+// A configuration that has been collapsed is collapsed again afterwards so the dotted keys see the
+// merged values.
+func (config Configuration) Merge(other Configuration, strategy MergeStrategy) {
+	collapsed := config.collapsed()
+	if collapsed {
+		config.uncollapse()
+	}
+
+	merger{strategy: strategy}.mergeMembers(config, nil, other, "")
+
+	if collapsed {
+		config.Collapse()
+	}
+}
+
+// Does the work for Merge, optionally telling someone about each value that changes.
+type merger struct {
+	strategy MergeStrategy
+	// Called with the dotted path of each value copied from the other config, along with the
+	// value it replaced, which is the zero JSONValue if there wasn't one, if set.
+	copied func(path string, old JSONValue, value JSONValue)
+	// Called with the dotted path of each value deleted from the calling config, if set.
+	deleted func(path string, old JSONValue)
+}
+
+// Merges the members of other into config. raw is the abstract map config was made from, which is
+// kept in step with it, and is nil for a top level Configuration.
+func (m merger) mergeMembers(config Configuration, raw map[string]interface{}, other Configuration, path string) {
+	for key, otherValue := range other {
+		if other.addedByCollapse(key) {
+			continue
+		}
+		valuePath := joinPath(path, key)
+		value, exists := config[key]
+		exists = exists && !config.addedByCollapse(key)
+
+		if !exists {
+			if !(m.strategy.NullDeletes && m.strategy.Override && otherValue.Kind() == KindNull) {
+				m.put(config, raw, key, otherValue, valuePath)
+			}
+			continue
+		}
+
+		winner := value
+		if m.strategy.Override {
+			winner = otherValue
+		}
+
+		switch {
+		case m.strategy.NullDeletes && winner.Kind() == KindNull:
+			delete(config, key)
+			if raw != nil {
+				delete(raw, key)
+			}
+			if m.deleted != nil {
+				m.deleted(valuePath, value)
+			}
+		case value.Kind() == KindObject && otherValue.Kind() == KindObject:
+			rawObject, _ := value.Value.(map[string]interface{})
+			m.mergeMembers(value.Obj, rawObject, otherValue.Obj, valuePath)
+		case value.Kind() == KindArray && otherValue.Kind() == KindArray && m.strategy.Arrays != ReplaceArrays:
+			m.put(config, raw, key, m.mergeArrays(value.Arr, otherValue.Arr), valuePath)
+		case m.strategy.Override:
+			m.put(config, raw, key, otherValue, valuePath)
+		}
+	}
+}
+
+// Sets a member of config, keeping raw in step.
+func (m merger) put(config Configuration, raw map[string]interface{}, key string, value JSONValue, path string) {
+	old := config[key]
+	config[key] = value
+	if raw != nil {
+		raw[key] = value.Value
+	}
+	if m.copied != nil {
+		m.copied(path, old, value)
+	}
+}
+
+// Combines two arrays according to the strategy, which must not be ReplaceArrays.
+func (m merger) mergeArrays(array []JSONValue, other []JSONValue) JSONValue {
+	merged := append([]JSONValue{}, array...)
+
+	switch m.strategy.Arrays {
+	case AppendArrays:
+		merged = append(merged, other...)
+	case AppendUniqueArrays:
+		for _, otherElement := range other {
+			if indexOfValue(merged, otherElement) < 0 {
+				merged = append(merged, otherElement)
+			}
+		}
+	case MergeArraysByIndex:
+		for i, otherElement := range other {
+			if i < len(merged) {
+				merged[i] = m.mergeElements(merged[i], otherElement)
+			} else {
+				merged = append(merged, otherElement)
+			}
+		}
+	case MergeArraysByKey:
+		for _, otherElement := range other {
+			if i := indexOfKey(merged, otherElement, m.strategy.KeyField); i >= 0 {
+				merged[i] = m.mergeElements(merged[i], otherElement)
+			} else {
+				merged = append(merged, otherElement)
+			}
+		}
+	}
+
+	raw := make([]interface{}, len(merged))
+	for i, element := range merged {
+		raw[i] = element.raw()
+	}
+	return NewJSONValue(raw)
+}
+
+// Merges two elements of arrays that have been matched up.
+func (m merger) mergeElements(element JSONValue, other JSONValue) JSONValue {
+	switch {
+	case element.Kind() == KindObject && other.Kind() == KindObject:
+		// Changes within an array are reported as a change to the whole array, so nobody is told
+		// about the members
+		merged := NewJSONValue(element.raw())
+		merger{strategy: m.strategy}.mergeMembers(merged.Obj, merged.Value.(map[string]interface{}), other.Obj, "")
+		return merged
+	case element.Kind() == KindArray && other.Kind() == KindArray && m.strategy.Arrays != MergeArraysByKey:
+		return m.mergeArrays(element.Arr, other.Arr)
+	case m.strategy.Override:
+		return other
+	default:
+		return element
+	}
+}
+
+// Returns the index of the first element equal to value, or -1 if there isn't one.
+func indexOfValue(array []JSONValue, value JSONValue) int {
+	raw := value.raw()
+	for i, element := range array {
+		if reflect.DeepEqual(element.raw(), raw) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Returns the index of the first object whose keyField member equals that of value, or -1 if
+// there isn't one or value has no such member.
+func indexOfKey(array []JSONValue, value JSONValue, keyField string) int {
+	key, exists := value.Obj[keyField]
+	if value.Kind() != KindObject || !exists {
+		return -1
+	}
+	for i, element := range array {
+		if elementKey, exists := element.Obj[keyField]; exists && element.Kind() == KindObject && reflect.DeepEqual(elementKey.raw(), key.raw()) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Rebuilds the abstract value from Obj and Arr, which can drift from Value as a configuration is
+// altered. Keys added by Collapse are left out.
+func (key JSONValue) raw() interface{} {
+	switch key.Kind() {
+	case KindObject:
+		return key.Obj.raw()
+	case KindArray:
+		array := make([]interface{}, len(key.Arr))
+		for i, element := range key.Arr {
+			array[i] = element.raw()
+		}
+		return array
+	default:
+		return key.Value
+	}
+}
+
+// Rebuilds the abstract map the configuration represents. Keys added by Collapse are left out.
+func (config Configuration) raw() map[string]interface{} {
+	object := make(map[string]interface{}, len(config))
+	for key, value := range config {
+		if !config.addedByCollapse(key) {
+			object[key] = value.raw()
+		}
+	}
+	return object
+}
@@ -0,0 +1,186 @@
+package jsonconfig_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/callum-ramage/jsonconfig"
+)
+
+// Renders the members of a config compactly for comparison.
+func compact(value interface{}) string {
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+func TestMergeArrays(test *testing.T) {
+	strategies := map[jsonconfig.ArrayMerge][2]string{
+		jsonconfig.ReplaceArrays:      {`[1,2,3]`, `[3,4]`},
+		jsonconfig.AppendArrays:       {`[1,2,3,3,4]`, `[1,2,3,3,4]`},
+		jsonconfig.AppendUniqueArrays: {`[1,2,3,4]`, `[1,2,3,4]`},
+		jsonconfig.MergeArraysByIndex: {`[1,2,3]`, `[3,4,3]`},
+	}
+
+	for arrays, expected := range strategies {
+		for i, override := range []bool{false, true} {
+			config, _ := jsonconfig.LoadString(`{"array": [1, 2, 3]}`, "")
+			other, _ := jsonconfig.LoadString(`{"array": [3, 4]}`, "")
+			config.Merge(other, jsonconfig.MergeStrategy{Override: override, Arrays: arrays})
+
+			if compact(config["array"].Value) != expected[i] || len(config["array"].Arr) != len(config["array"].Value.([]interface{})) {
+				fmt.Println(arrays, override, compact(config["array"].Value))
+				test.Error()
+			}
+		}
+	}
+}
+
+func TestMergeArraysByKey(test *testing.T) {
+	config, _ := jsonconfig.LoadString(`{"backends": [
+		{"name": "a", "weight": 1, "tags": ["x"]},
+		{"name": "b", "weight": 1},
+		"not an object"
+	]}`, "")
+	other, _ := jsonconfig.LoadString(`{"backends": [
+		{"name": "b", "weight": 5, "enabled": false},
+		{"name": "c", "weight": 1},
+		{"weight": 2}
+	]}`, "")
+
+	config.Merge(other, jsonconfig.MergeStrategy{Override: true, Arrays: jsonconfig.MergeArraysByKey, KeyField: "name"})
+
+	expected := `[{"name":"a","tags":["x"],"weight":1},{"enabled":false,"name":"b","weight":5},"not an object",{"name":"c","weight":1},{"weight":2}]`
+	if compact(config["backends"].Value) != expected {
+		fmt.Println(compact(config["backends"].Value))
+		test.Error()
+	}
+
+	if config["backends"].Arr[1].Obj["weight"].Int != 5 {
+		fmt.Println(config["backends"].Arr[1].Obj)
+		test.Error()
+	}
+}
+
+func TestMergeNullDeletes(test *testing.T) {
+	config, _ := jsonconfig.LoadString(`{"a": 1, "b": {"c": 2, "d": 3}, "e": null}`, "")
+	other, _ := jsonconfig.LoadString(`{"a": null, "b": {"c": null}, "e": 4, "f": null}`, "")
+	config.Merge(other, jsonconfig.MergeStrategy{Override: true, NullDeletes: true})
+
+	if compact(config.Get("b").Value) != `{"d":3}` || config["e"].Int != 4 {
+		fmt.Println(compact(config.Get("b").Value), config["e"].Value)
+		test.Error()
+	}
+
+	for _, key := range []string{"a", "f"} {
+		if _, exists := config[key]; exists {
+			fmt.Println(key)
+			test.Error()
+		}
+	}
+
+	// When the calling config wins its own nulls delete the keys
+	config, _ = jsonconfig.LoadString(`{"a": null, "b": 1}`, "")
+	other, _ = jsonconfig.LoadString(`{"a": 1, "b": null, "c": null}`, "")
+	config.Merge(other, jsonconfig.MergeStrategy{NullDeletes: true})
+
+	if _, exists := config["a"]; exists || config["b"].Int != 1 || config["c"].Kind() != jsonconfig.KindNull {
+		fmt.Println(config)
+		test.Error()
+	}
+}
+
+func TestMergeOverride(test *testing.T) {
+	config, _ := jsonconfig.LoadAbstract("./configs/ExampleConfig1.conf", "")
+	other, _ := jsonconfig.LoadAbstract("./configs/ExampleConfig2.conf", "")
+	config.Merge(other, jsonconfig.MergeStrategy{Override: true})
+
+	if config["collision"].Str != "two" || config["object collision.collision"].Str != "two" || config["object collision.from one"].Int != 1 {
+		fmt.Println(config["collision"].Str, config["object collision.collision"].Str, config["object collision.from one"].Int)
+		test.Error()
+	}
+
+	if config["array collision.0"].Str != "two" || config["array collision.1"].Str != "three" {
+		fmt.Println(config["array collision"].Arr)
+		test.Error()
+	}
+}
+
+func TestLayerStrategy(test *testing.T) {
+	config, sources, err := jsonconfig.LoadLayersWithOptions([]string{
+		"./configs/layers/Base.conf",
+		"./configs/layers/Production.conf",
+	}, jsonconfig.LayerStrategy(jsonconfig.MergeStrategy{Arrays: jsonconfig.AppendArrays}))
+
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	if len(config["upstreams"].Arr) != 3 || config["upstreams.2"].Str != "c.internal" || sources.Of("upstreams.0") != "./configs/layers/Production.conf" {
+		fmt.Println(config["upstreams"].Arr, sources.Of("upstreams.0"))
+		test.Error()
+	}
+
+	if config["server.port"].Int != 443 {
+		fmt.Println(config["server.port"].Int)
+		test.Error()
+	}
+}
+
+func ExampleConfiguration_Merge() {
+	/*
+	  ./configs/ExampleConfig1.conf is
+	  {
+	    "from one": 1,
+	    "collision": "one",
+	    "object collision": {
+	      "from one": 1,
+	      "collision": "one"
+	    },
+	    "array collision": [
+	      "one"
+	    ]
+	  }
+
+	  ./configs/ExampleConfig2.conf is
+	  {
+	    "from two": 2,
+	    "collision": "two",
+	    "object collision": {
+	      "from two": 2,
+	      "collision": "two"
+	    },
+	    "array collision": [
+	      "two",
+	      "three"
+	    ]
+	  }
+	*/
+	config, err := jsonconfig.LoadAbstract("./configs/ExampleConfig1.conf", "")
+
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	config2, err := jsonconfig.LoadAbstract("./configs/ExampleConfig2.conf", "")
+
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	config.Merge(config2, jsonconfig.MergeStrategy{Override: true, Arrays: jsonconfig.AppendArrays})
+
+	fmt.Println("collision:", config["collision"].Str)
+	fmt.Println("object collision.collision:", config["object collision.collision"].Str)
+	fmt.Println("length of array collision:", len(config["array collision"].Arr))
+	fmt.Println("array collision.2:", config["array collision.2"].Str)
+
+	// Output: collision: two
+	// object collision.collision: two
+	// length of array collision: 3
+	// array collision.2: three
+}
@@ -0,0 +1,302 @@
+package jsonconfig
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Describes a value from outside the configuration file, such as an environment variable or a
+// command line flag, that couldn't be applied to the configuration.
+type OverrideError struct {
+	// The dotted path of the value being overridden.
+	Path string
+	// Where the new value came from, such as "environment variable APP_PORT".
+	Source string
+	Err    error
+}
+
+func (e *OverrideError) Error() string {
+	return fmt.Sprintf("jsonconfig: %s (from %s): %v", e.Path, e.Source, e.Err)
+}
+
+func (e *OverrideError) Unwrap() error {
+	return e.Err
+}
+
+// Converts text into a JSONValue of the same kind as existing. Strings are taken as they are,
+// numbers and bools are parsed, and arrays and objects must be written as JSON. When there's
+// no existing value to go by, text is parsed as JSON if it can be and is a string otherwise.
+func coerceValue(existing JSONValue, text string) (JSONValue, error) {
+	switch kind := existing.Kind(); kind {
+	case KindString:
+		return NewJSONValue(text), nil
+	case KindBool:
+		value, err := strconv.ParseBool(strings.TrimSpace(text))
+		if err != nil {
+			return JSONValue{}, fmt.Errorf("%q is not a bool", text)
+		}
+		return NewJSONValue(value), nil
+	case KindNumber:
+		value, err := parseJSONText(strings.TrimSpace(text), isNumber)
+		if err != nil {
+			return JSONValue{}, fmt.Errorf("%q is not a number", text)
+		}
+		if _, isFloat := existing.Value.(float64); isFloat {
+			number, _ := value.(json.Number).Float64()
+			return NewJSONValue(number), nil
+		}
+		return NewJSONValue(value), nil
+	case KindArray, KindObject:
+		value, err := parseJSONText(text, nil)
+		if err != nil || NewJSONValue(value).Kind() != kind {
+			return JSONValue{}, fmt.Errorf("%q is not a JSON %s", text, kind)
+		}
+		return NewJSONValue(value), nil
+	default:
+		if value, err := parseJSONText(text, nil); err == nil {
+			return NewJSONValue(value), nil
+		}
+		return NewJSONValue(text), nil
+	}
+}
+
+// Parses text as a single JSON value, keeping numbers as json.Number. If check is given the
+// value must also satisfy it.
+func parseJSONText(text string, check func(interface{}) bool) (interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected text after %q", text)
+	}
+	if check != nil && !check(value) {
+		return nil, fmt.Errorf("unexpected value %q", text)
+	}
+	return value, nil
+}
+
+// Reports whether the abstract value is a number.
+func isNumber(value interface{}) bool {
+	return NewJSONValue(value).Kind() == KindNumber
+}
+
+// Replaces a member of an object, keeping the abstract Value in step with Obj.
+func (key JSONValue) setMember(name string, value JSONValue) {
+	key.Obj[name] = value
+	if object, ok := key.Value.(map[string]interface{}); ok {
+		object[name] = value.Value
+	}
+}
+
+// Replaces an element of an array, keeping the abstract Value in step with Arr.
+func (key JSONValue) setElement(index int, value JSONValue) {
+	key.Arr[index] = value
+	if array, ok := key.Value.([]interface{}); ok && index < len(array) {
+		array[index] = value.Value
+	}
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+var durationType = reflect.TypeOf(time.Duration(0))
+var numberType = reflect.TypeOf(json.Number(""))
+
+// Sets a field of a struct being loaded from text, converting the text to the field's type.
+// Types implementing encoding.TextUnmarshaler are handed the text, time.Duration accepts
+// values such as "30s", and anything that isn't a simple value must be written as JSON.
+func setFromText(field reflect.Value, text string) error {
+	if field.CanAddr() && field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text))
+	}
+
+	trimmed := strings.TrimSpace(text)
+	switch {
+	case field.Type() == durationType:
+		duration, err := time.ParseDuration(trimmed)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration", text)
+		}
+		field.SetInt(int64(duration))
+		return nil
+	case field.Type() == numberType:
+		if _, err := parseJSONText(trimmed, isNumber); err != nil {
+			return fmt.Errorf("%q is not a number", text)
+		}
+		field.SetString(trimmed)
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(text)
+	case reflect.Bool:
+		value, err := strconv.ParseBool(trimmed)
+		if err != nil {
+			return fmt.Errorf("%q is not a bool", text)
+		}
+		field.SetBool(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value, err := strconv.ParseInt(trimmed, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a %s", text, field.Type())
+		}
+		field.SetInt(value)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		value, err := strconv.ParseUint(trimmed, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a %s", text, field.Type())
+		}
+		field.SetUint(value)
+	case reflect.Float32, reflect.Float64:
+		value, err := strconv.ParseFloat(trimmed, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a %s", text, field.Type())
+		}
+		field.SetFloat(value)
+	case reflect.Interface:
+		if field.NumMethod() != 0 {
+			return fmt.Errorf("cannot set a %s from text", field.Type())
+		}
+		value, err := parseJSONText(text, nil)
+		if err != nil {
+			value = text
+		}
+		field.Set(reflect.ValueOf(&value).Elem())
+	default:
+		target := reflect.New(field.Type())
+		if err := json.Unmarshal([]byte(text), target.Interface()); err != nil {
+			return fmt.Errorf("%q is not a JSON %s", text, field.Type())
+		}
+		field.Set(target.Elem())
+	}
+	return nil
+}
+
+// Returns the name encoding/json uses for a struct field, or false if encoding/json ignores it.
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() && !field.Anonymous {
+		return "", false
+	}
+
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name, true
+	}
+	return field.Name, true
+}
+
+// Reports whether a struct field is embedded in a way that encoding/json promotes its fields
+// into the parent object.
+func isPromoted(field reflect.StructField) bool {
+	if !field.Anonymous {
+		return false
+	}
+	if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name != "" {
+		return false
+	}
+	fieldType := field.Type
+	if fieldType.Kind() == reflect.Pointer {
+		fieldType = fieldType.Elem()
+	}
+	return fieldType.Kind() == reflect.Struct
+}
+
+// Finds the field of a struct that encoding/json would decode the key into, looking through
+// embedded structs. As with encoding/json an exact match is preferred over a case insensitive one.
+func fieldByJSONName(value reflect.Value, key string) (reflect.Value, bool) {
+	if field, ok := findJSONField(value, key, false); ok {
+		return field, true
+	}
+	return findJSONField(value, key, true)
+}
+
+// Does the work for fieldByJSONName, matching either exactly or ignoring case.
+func findJSONField(value reflect.Value, key string, ignoreCase bool) (reflect.Value, bool) {
+	for i := 0; i < value.NumField(); i++ {
+		fieldInfo := value.Type().Field(i)
+		field := value.Field(i)
+
+		if isPromoted(fieldInfo) {
+			if field.Kind() == reflect.Pointer {
+				if field.IsNil() {
+					continue
+				}
+				field = field.Elem()
+			}
+			if found, ok := findJSONField(field, key, ignoreCase); ok {
+				return found, true
+			}
+			continue
+		}
+
+		name, ok := jsonFieldName(fieldInfo)
+		if !ok || !fieldInfo.IsExported() {
+			continue
+		}
+		if name == key || (ignoreCase && strings.EqualFold(name, key)) {
+			return field, true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// Sets the value found by following the path from value, converting text to its type. Nil
+// pointers and maps along the way are allocated, and new map entries are created as needed.
+func assignField(value reflect.Value, segments []string, text string) error {
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+		value = value.Elem()
+	}
+
+	if len(segments) == 0 {
+		return setFromText(value, text)
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		if field, ok := fieldByJSONName(value, segments[0]); ok {
+			return assignField(field, segments[1:], text)
+		}
+	case reflect.Slice, reflect.Array:
+		if index, ok := arrayIndex(segments[0], value.Len()); ok {
+			return assignField(value.Index(index), segments[1:], text)
+		}
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if value.IsNil() {
+			value.Set(reflect.MakeMap(value.Type()))
+		}
+
+		return updateMapEntry(value, segments[0], func(entry reflect.Value) error {
+			return assignField(entry, segments[1:], text)
+		})
+	case reflect.Interface:
+		// An empty interface starts out as an object, as it would when decoding one
+		if value.IsNil() && value.NumMethod() == 0 {
+			value.Set(reflect.ValueOf(map[string]interface{}{}))
+		}
+		if value.IsNil() {
+			break
+		}
+
+		return updateInterface(value, func(held reflect.Value) error {
+			return assignField(held, segments, text)
+		})
+	}
+	return ErrUnknownPath
+}
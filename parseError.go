@@ -0,0 +1,135 @@
+package jsonconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Describes a configuration that couldn't be parsed, pointing at where the problem is in the
+// original text (before comments and trailing commas were removed).
+type ParseError struct {
+	// The file being parsed, or empty when parsing a string.
+	Filename string
+	// The line and column of the problem, both counting from 1. Columns count characters, so a
+	// tab is a single column.
+	Line   int
+	Column int
+	// The byte offset of the problem within the original text.
+	Offset int64
+	// The offending line followed by a second line with a ^ under the problem.
+	Excerpt string
+	// The underlying error reported by the decoder.
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	if e.Filename == "" {
+		return fmt.Sprintf("jsonconfig: line %d, column %d: %v", e.Line, e.Column, e.Err)
+	}
+	return fmt.Sprintf("%s:%d:%d: %v", e.Filename, e.Line, e.Column, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Creates a ParseError for the byte offset within source.
+func newParseError(filename string, source []byte, offset int64, err error) *ParseError {
+	if offset > int64(len(source)) {
+		offset = int64(len(source))
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	lineStart := strings.LastIndexByte(string(source[:offset]), '\n') + 1
+	lineEnd := strings.IndexByte(string(source[lineStart:]), '\n')
+	if lineEnd < 0 {
+		lineEnd = len(source)
+	} else {
+		lineEnd += lineStart
+	}
+	line := strings.TrimSuffix(string(source[lineStart:lineEnd]), "\r")
+	prefix := source[lineStart:offset]
+
+	// Keep any tabs in the marker line so that the ^ lines up however tabs are displayed
+	marker := strings.Map(func(character rune) rune {
+		if character == '\t' {
+			return '\t'
+		}
+		return ' '
+	}, string(prefix))
+
+	return &ParseError{
+		Filename: filename,
+		Line:     strings.Count(string(source[:offset]), "\n") + 1,
+		Column:   utf8.RuneCount(prefix) + 1,
+		Offset:   offset,
+		Excerpt:  line + "\n" + marker + "^",
+		Err:      err,
+	}
+}
+
+// Wraps errors that carry an offset into the processed text in a ParseError locating the problem
+// in the original source. Other errors are returned as they are.
+func locateError(filename string, source []byte, processor *preProcessor, err error) error {
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	var json5Error *json5SyntaxError
+
+	switch {
+	case errors.As(err, &syntaxError):
+		// The offset is just past the character that caused the problem
+		return newParseError(filename, source, processor.OriginalOffset(syntaxError.Offset-1), err)
+	case errors.As(err, &typeError):
+		return newParseError(filename, source, processor.OriginalOffset(typeError.Offset), err)
+	case errors.As(err, &json5Error):
+		return newParseError(filename, source, json5Error.Offset, err)
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF), errors.Is(err, ErrUnterminatedComment):
+		return newParseError(filename, source, int64(len(source)), err)
+	}
+	return err
+}
+
+// Wraps a type error from decoding values that were encoded again after parsing in a ParseError.
+// The offset within the encoded text means nothing in the original source, so the error points
+// at the key of the offending value instead. Values with no key of their own, such as a
+// top level value, can't be located and their errors are returned as they are.
+func locateTypeError(filename string, source []byte, options *loadOptions, err error) error {
+	var typeError *json.UnmarshalTypeError
+	if !errors.As(err, &typeError) || typeError.Field == "" {
+		return err
+	}
+
+	offsets := keyOffsets(source, options)
+	offset, found := offsets[typeError.Field]
+	if !found {
+		// Older versions of encoding/json leave array indices out of the field, and dots in keys
+		// are never escaped in it, so take the first key that matches once they're left out
+		for path, pathOffset := range offsets {
+			if withoutIndices(strings.Join(splitPath(path), ".")) == typeError.Field && (!found || pathOffset < offset) {
+				offset, found = pathOffset, true
+			}
+		}
+	}
+	if !found {
+		return err
+	}
+	return newParseError(filename, source, offset, err)
+}
+
+// Removes the array indices from a dotted path.
+func withoutIndices(path string) string {
+	var kept []string
+	for _, segment := range strings.Split(path, ".") {
+		if _, err := strconv.Atoi(segment); err != nil {
+			kept = append(kept, segment)
+		}
+	}
+	return strings.Join(kept, ".")
+}
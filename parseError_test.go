@@ -0,0 +1,101 @@
+package jsonconfig_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/callum-ramage/jsonconfig"
+)
+
+func TestParseErrorLocation(test *testing.T) {
+	_, err := jsonconfig.LoadAbstract("./configs/InvalidConfig.conf", "")
+
+	var parseError *jsonconfig.ParseError
+	if !errors.As(err, &parseError) {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	if parseError.Filename != "./configs/InvalidConfig.conf" || parseError.Line != 5 || parseError.Column != 18 {
+		fmt.Println(parseError.Filename, parseError.Line, parseError.Column)
+		test.Error()
+	}
+
+	if parseError.Excerpt != "\t\"array value 0\",,\n\t                ^" {
+		fmt.Printf("%q\n", parseError.Excerpt)
+		test.Error()
+	}
+
+	if err.Error() != "./configs/InvalidConfig.conf:5:18: invalid character ',' looking for beginning of value" {
+		fmt.Println(err)
+		test.Error()
+	}
+}
+
+func TestParseErrorString(test *testing.T) {
+	invalid := map[string][2]int{
+		"{\n  \"a\": 1,\n  \"b\": }":               {3, 8},
+		"{\n  \"a\": [1, 2,\n  ] // \n  \"b\": 2}": {4, 3},
+		"{\n  \"a\": \"é\" x}":                     {2, 12},
+		"{\n  \"a\": 1":                            {2, 9},
+	}
+
+	for source, location := range invalid {
+		_, err := jsonconfig.LoadString(source, "")
+
+		var parseError *jsonconfig.ParseError
+		if !errors.As(err, &parseError) {
+			fmt.Println(err)
+			test.Error()
+			continue
+		}
+
+		if parseError.Line != location[0] || parseError.Column != location[1] {
+			fmt.Printf("%q %d:%d\n", source, parseError.Line, parseError.Column)
+			test.Error()
+		}
+	}
+}
+
+func TestParseErrorJSON5(test *testing.T) {
+	_, err := jsonconfig.LoadString("{\n  a: 1,\n  b: 01,\n}", "", jsonconfig.JSON5())
+
+	var parseError *jsonconfig.ParseError
+	if !errors.As(err, &parseError) || parseError.Line != 3 || parseError.Column != 7 {
+		fmt.Println(err)
+		test.Error()
+	}
+}
+
+func TestParseErrorLoad(test *testing.T) {
+	config := struct {
+		Example_string int
+	}{}
+
+	err := jsonconfig.Load("./configs/ExampleConfig.conf", &config)
+
+	var parseError *jsonconfig.ParseError
+	if !errors.As(err, &parseError) || parseError.Line != 2 {
+		fmt.Println(err)
+		test.Error()
+	}
+}
+
+func TestParseErrorLoadTransformed(test *testing.T) {
+	config := struct {
+		Example_object struct {
+			Example_number string
+		}
+	}{}
+
+	// Expanding the environment means the values are decoded a second time
+	err := jsonconfig.Load("./configs/ExampleConfig.conf", &config, jsonconfig.ExpandEnv(nil))
+
+	var parseError *jsonconfig.ParseError
+	if !errors.As(err, &parseError) || parseError.Line != 7 {
+		fmt.Println(err)
+		test.Error()
+	}
+}
@@ -0,0 +1,114 @@
+package jsonconfig
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Splits a dotted path into its parts. A dot preceded by a backslash is part of a key rather than
+// a separator, and a double backslash is a single backslash, so the path a\.b.c is the key "a.b"
+// followed by the key "c".
+func splitPath(path string) []string {
+	if !strings.Contains(path, "\\") {
+		return strings.Split(path, ".")
+	}
+
+	var segments []string
+	var segment strings.Builder
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path) && (path[i+1] == '.' || path[i+1] == '\\'):
+			i++
+			segment.WriteByte(path[i])
+		case path[i] == '.':
+			segments = append(segments, segment.String())
+			segment.Reset()
+		default:
+			segment.WriteByte(path[i])
+		}
+	}
+	return append(segments, segment.String())
+}
+
+// Escapes any dots and backslashes within a key so that it can be used as part of a dotted path.
+func EscapeKey(key string) string {
+	return strings.NewReplacer("\\", "\\\\", ".", "\\.").Replace(key)
+}
+
+// Joins the first count segments back into a single key, as a key containing dots is matched
+// before the path is split. A segment holding a dot was written with the dot escaped, which shows
+// exactly where that key ends, so it's never joined with the segments around it.
+func joinSegments(segments []string, count int) (string, bool) {
+	if count == 1 {
+		return segments[0], true
+	}
+	for _, segment := range segments[:count] {
+		if strings.Contains(segment, ".") {
+			return "", false
+		}
+	}
+	return strings.Join(segments[:count], "."), true
+}
+
+// Turns a path segment into an index into an array of the length. Negative indices count back
+// from the end, so -1 is the last element.
+func arrayIndex(segment string, length int) (int, bool) {
+	index, err := strconv.Atoi(segment)
+	if err != nil {
+		return 0, false
+	}
+	if index < 0 {
+		index += length
+	}
+	return index, index >= 0 && index < length
+}
+
+// Finds the value at a dotted path along with a func that replaces it. As with Get, a key
+// containing dots is matched before it's split, and array elements are matched by index,
+// counting back from the end for negative indices. Keys added by Collapse are skipped so that
+// the value found is always the one in the tree.
+func (config Configuration) resolve(path string) (JSONValue, func(JSONValue), bool) {
+	return resolveMembers(config, splitPath(path), func(key string, value JSONValue) {
+		config[key] = value
+	})
+}
+
+// Finds the value at the path within an object. set is used to replace a member.
+func resolveMembers(members Configuration, segments []string, set func(string, JSONValue)) (JSONValue, func(JSONValue), bool) {
+	for i := len(segments); i > 0; i-- {
+		key, ok := joinSegments(segments, i)
+		if !ok {
+			continue
+		}
+		child, exists := members[key]
+		if !exists || members.addedByCollapse(key) {
+			continue
+		}
+
+		if i == len(segments) {
+			return child, func(value JSONValue) { set(key, value) }, true
+		}
+		if value, replace, ok := resolveValue(child, segments[i:]); ok {
+			return value, replace, true
+		}
+	}
+	return JSONValue{}, nil, false
+}
+
+// Finds the value at the path within an object or array.
+func resolveValue(value JSONValue, segments []string) (JSONValue, func(JSONValue), bool) {
+	switch value.Kind() {
+	case KindObject:
+		return resolveMembers(value.Obj, segments, value.setMember)
+	case KindArray:
+		index, ok := arrayIndex(segments[0], len(value.Arr))
+		if !ok {
+			return JSONValue{}, nil, false
+		}
+		if len(segments) == 1 {
+			return value.Arr[index], func(element JSONValue) { value.setElement(index, element) }, true
+		}
+		return resolveValue(value.Arr[index], segments[1:])
+	}
+	return JSONValue{}, nil, false
+}
@@ -0,0 +1,91 @@
+package jsonconfig_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/callum-ramage/jsonconfig"
+)
+
+func TestGetArrays(test *testing.T) {
+	config, err := jsonconfig.LoadAbstractNoCollapse("./configs/TestConfig.conf", "")
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	expected := map[string]interface{}{
+		"test_array.0":              "array value 0",
+		"test_array.1.array value":  float64(1),
+		"test_array.-1.array value": float64(1),
+		"test_array.-2":             "array value 0",
+		"test_object.test_number":   5.3,
+		"pl//ace":                   "valid json",
+		"test_array.2":              nil,
+		"test_array.-3":             nil,
+		"test_array.x":              nil,
+		"test_string.0":             nil,
+	}
+	for path, value := range expected {
+		if config.Get(path).Value != value {
+			fmt.Println(path, config.Get(path).Value)
+			test.Error()
+		}
+	}
+}
+
+func TestGetEscapedDots(test *testing.T) {
+	config, _ := jsonconfig.LoadString(`{
+		"example": {"collision": "ignored", "only": "nested"},
+		"example.collision": "used",
+		"a.b": {"c\\d": {"e": 1}}
+	}`, "")
+
+	expected := map[string]interface{}{
+		"example.collision":  "used",
+		`example\.collision`: "used",
+		"example.only":       "nested",
+		`example\.only`:      nil,
+		`a\.b.c\\d.e`:        float64(1),
+		"a.b.c\\d.e":         float64(1),
+		jsonconfig.EscapeKey("a.b") + "." + jsonconfig.EscapeKey(`c\d`) + ".e": float64(1),
+	}
+	for path, value := range expected {
+		if config.Get(path).Value != value {
+			fmt.Println(path, config.Get(path).Value)
+			test.Error()
+		}
+	}
+
+	// A key written with escaped dots isn't joined with the keys after it
+	config, _ = jsonconfig.LoadString(`{"a.b.c": 1, "a.b": {"c": 2}}`, "")
+	if config.Get(`a\.b.c`).Value != float64(2) || config.Get(`a\.b\.c`).Value != float64(1) || config.Get("a.b.c").Value != float64(1) {
+		fmt.Println(config.Get(`a\.b.c`).Value, config.Get(`a\.b\.c`).Value, config.Get("a.b.c").Value)
+		test.Error()
+	}
+
+	if jsonconfig.EscapeKey(`a.b\c`) != `a\.b\\c` {
+		fmt.Println(jsonconfig.EscapeKey(`a.b\c`))
+		test.Error()
+	}
+}
+
+func TestNegativeIndexAssignments(test *testing.T) {
+	config, _ := jsonconfig.LoadAbstract("./configs/TestConfig.conf", "")
+	assignments, _ := jsonconfig.ParseAssignments([]string{"test_array.-2=changed"})
+	if err := assignments.Apply(config); err != nil || config.Get("test_array.0").Str != "changed" {
+		fmt.Println(err, config.Get("test_array.0").Value)
+		test.Error()
+	}
+
+	var target struct {
+		Values []int `json:"values"`
+	}
+	target.Values = []int{1, 2, 3}
+	assignments, _ = jsonconfig.ParseAssignments([]string{"values.-1=4"})
+	if err := assignments.ApplyTo(&target); err != nil || target.Values[2] != 4 {
+		fmt.Println(err, target.Values)
+		test.Error()
+	}
+}
@@ -1,25 +1,59 @@
 package jsonconfig
 
 import (
+	"errors"
 	"io"
+	"sort"
 )
 
-// Outputs json with //comments removed.
+// Is returned by a JsonCommentStripper when the source ends part way through a /* block comment */.
+var ErrUnterminatedComment = errors.New("jsonconfig: unterminated block comment")
+
+// The states the comment stripper can be in between two bytes of input.
+type stripperState int
+
+const (
+	stateNormal stripperState = iota
+	stateString
+	stateStringEscape
+	stateSlash
+	stateLineComment
+	stateBlockComment
+	stateBlockCommentStar
+)
+
+// Outputs json with //comments and /* block comments */ removed.
 type JsonCommentStripper struct {
-	R                 io.Reader
-	b                 []byte
-	pos               int
-	end               int
-	err               error
-	withinString      bool
-	previousCharacter byte
-	more              bool
+	R            io.Reader
+	b            []byte
+	pos          int
+	end          int
+	err          error
+	state        stripperState
+	more         bool
+	hashComments bool
+	offset       int64
+	offsets      offsetMap
+}
+
+// Alters the behaviour of a JsonCommentStripper.
+type StripperOption func(*JsonCommentStripper)
+
+// Makes the comment stripper treat # as the start of a comment that runs to the end of the line.
+// This is off by default because # isn't a comment in any of the JSON dialects.
+func StripHashComments() StripperOption {
+	return func(j *JsonCommentStripper) {
+		j.hashComments = true
+	}
 }
 
 // Creates a new comment stripper that can be used as an intermediate layer between
 // a JSON decoder and a json source reader.
-func NewJsonCommentStripper(reader io.Reader) *JsonCommentStripper {
-	commentStripper := JsonCommentStripper{reader, make([]byte, 10000), 0, 0, nil, false, 0, true}
+func NewJsonCommentStripper(reader io.Reader, options ...StripperOption) *JsonCommentStripper {
+	commentStripper := JsonCommentStripper{R: reader, b: make([]byte, 10000), more: true}
+	for _, option := range options {
+		option(&commentStripper)
+	}
 	commentStripper.fillBuffer()
 	return &commentStripper
 }
@@ -37,68 +71,277 @@ func (j *JsonCommentStripper) fillBuffer() {
 	}
 }
 
-// Reads data from the internal reader, removing //comments as it goes.
+// Reads data from the internal reader, removing //comments and /* block comments */ as it goes
+// (and #comments if enabled). Comments can't occur within a string, so "//" and "/*" inside a
+// string are left alone. A block comment is replaced by a single space so that the tokens either
+// side of it aren't joined.
 func (j *JsonCommentStripper) Read(p []byte) (n int, err error) {
-	// Track strings and // comments
-	// A comment can't occur within a string
-	// Nothing can happen after a comment
-	if j.pos == j.end && (j.more || j.err == nil) {
-		j.fillBuffer()
-	}
+	for n < len(p) {
+		if j.pos == j.end {
+			// Hand back what we have before blocking on the reader again
+			if n > 0 || !j.more {
+				break
+			}
+			j.fillBuffer()
+			continue
+		}
 
-	previousCharacter := j.previousCharacter
-	commentFound := false
-	start := j.pos
-	end := j.pos
+		character := j.b[j.pos]
+		characterOffset := j.offset
+		j.pos++
+		j.offset++
 
-	for i := j.pos; i <= j.end && cap(p) >= (i-start) && !commentFound; i++ {
-		end = i
-		if i != j.end {
-			if j.b[i] == '"' && previousCharacter != '\\' {
-				j.withinString = !j.withinString
+		switch j.state {
+		case stateNormal:
+			switch character {
+			case '"':
+				j.state = stateString
+			case '/':
+				j.state = stateSlash
+				continue
+			case '#':
+				if j.hashComments {
+					j.state = stateLineComment
+					continue
+				}
+			}
+			n = j.emit(p, n, character, characterOffset)
+		case stateString:
+			switch character {
+			case '\\':
+				j.state = stateStringEscape
+			case '"':
+				j.state = stateNormal
+			}
+			n = j.emit(p, n, character, characterOffset)
+		case stateStringEscape:
+			j.state = stateString
+			n = j.emit(p, n, character, characterOffset)
+		case stateSlash:
+			switch character {
+			case '/':
+				j.state = stateLineComment
+			case '*':
+				j.state = stateBlockComment
+			default:
+				// It wasn't a comment after all, so emit the held back slash and look at this
+				// character again on the next pass.
+				j.pos--
+				j.offset--
+				j.state = stateNormal
+				n = j.emit(p, n, '/', characterOffset-1)
+			}
+		case stateLineComment:
+			// The newline ending a comment is kept
+			if character == '\n' {
+				j.state = stateNormal
+				n = j.emit(p, n, character, characterOffset)
+			}
+		case stateBlockComment:
+			if character == '*' {
+				j.state = stateBlockCommentStar
 			}
-			if !j.withinString && j.b[i] == '/' && previousCharacter == '/' {
-				commentFound = true
+		case stateBlockCommentStar:
+			switch character {
+			case '/':
+				j.state = stateNormal
+				n = j.emit(p, n, ' ', characterOffset)
+			case '*':
+			default:
+				j.state = stateBlockComment
 			}
-			previousCharacter = j.b[i]
 		}
 	}
 
-	j.pos = end
-	j.previousCharacter = previousCharacter
+	if j.pos == j.end && !j.more {
+		switch j.state {
+		case stateSlash:
+			// A lone slash at the very end of the input
+			if n < len(p) {
+				j.state = stateNormal
+				n = j.emit(p, n, '/', j.offset-1)
+			}
+		case stateBlockComment, stateBlockCommentStar:
+			return n, ErrUnterminatedComment
+		}
+		if j.state != stateSlash {
+			err = j.err
+		}
+	}
+
+	return
+}
+
+// Writes a character to p, noting which byte of the input it came from.
+func (j *JsonCommentStripper) emit(p []byte, n int, character byte, inputOffset int64) int {
+	j.offsets.record(inputOffset, 1)
+	p[n] = character
+	return n + 1
+}
+
+// Maps an offset in the stripped output back to the offset of the same byte in the original
+// input, which is what you want when reporting the location of a problem to a person.
+func (j *JsonCommentStripper) OriginalOffset(offset int64) int64 {
+	return j.offsets.lookup(offset)
+}
+
+// Outputs json with any trailing commas before a ] or } removed. It expects comments to have
+// already been removed, so is normally layered over a JsonCommentStripper.
+type JsonTrailingCommaStripper struct {
+	R             io.Reader
+	b             []byte
+	out           []byte
+	outPos        int
+	pending       []byte
+	pendingOffset int64
+	err           error
+	withinString  bool
+	escaped       bool
+	previous      byte
+	offset        int64
+	offsets       offsetMap
+}
+
+// Creates a new trailing comma stripper that can be used as an intermediate layer between
+// a JSON decoder and a json source reader.
+func NewJsonTrailingCommaStripper(reader io.Reader) *JsonTrailingCommaStripper {
+	return &JsonTrailingCommaStripper{R: reader, b: make([]byte, 10000)}
+}
+
+// Reads data from the internal reader, removing trailing commas as it goes. A comma is held
+// back until the next non whitespace character shows whether it was trailing or not.
+func (j *JsonTrailingCommaStripper) Read(p []byte) (n int, err error) {
+	for j.outPos == len(j.out) && j.err == nil {
+		j.out = j.out[:0]
+		j.outPos = 0
 
-	if commentFound {
-		end--
+		end, readErr := j.R.Read(j.b)
+		for _, character := range j.b[:end] {
+			j.process(character)
+			j.offset++
+		}
+
+		if readErr != nil {
+			j.err = readErr
+			// A comma at the very end of the input is left for the decoder to complain about
+			j.emit(j.pending, j.pendingOffset)
+			j.pending = j.pending[:0]
+		}
 	}
 
-	copy(p, j.b[start:end])
-	n = end - start
+	n = copy(p, j.out[j.outPos:])
+	j.outPos += n
 
-	if !j.more {
-		err = io.EOF
+	if j.outPos == len(j.out) {
+		err = j.err
 	}
+	return
+}
 
-	// Advance to the end of the comment in preparation for the next read
-	if commentFound {
-		for i := end; commentFound; {
-			if i == j.end {
-				if j.more {
-					j.fillBuffer()
-					i = 0
-				} else {
-					err = j.err
-					j.pos = j.end
-					commentFound = false
-				}
-			} else {
-				if j.b[i] == byte(10) {
-					commentFound = false
-					j.pos = i
-				}
-				i++
-			}
+// Moves a single character of input into the output, or into the pending comma.
+func (j *JsonTrailingCommaStripper) process(character byte) {
+	if !j.withinString && len(j.pending) > 0 {
+		switch character {
+		case ' ', '\t', '\n', '\r':
+			j.pending = append(j.pending, character)
+			return
+		case ']', '}':
+			j.emit(j.pending[1:], j.pendingOffset+1)
+		default:
+			j.emit(j.pending, j.pendingOffset)
+			j.previous = ','
 		}
+		j.pending = j.pending[:0]
 	}
 
-	return
+	if j.withinString {
+		if j.escaped {
+			j.escaped = false
+		} else if character == '\\' {
+			j.escaped = true
+		} else if character == '"' {
+			j.withinString = false
+		}
+	} else if character == '"' {
+		j.withinString = true
+	} else if character == ',' && j.previous != ',' && j.previous != '[' && j.previous != '{' {
+		// A comma that doesn't follow a value is invalid either way, so it's passed straight
+		// through for the decoder to report.
+		j.pending = append(j.pending, character)
+		j.pendingOffset = j.offset
+		return
+	}
+
+	if character != ' ' && character != '\t' && character != '\n' && character != '\r' {
+		j.previous = character
+	}
+	j.offsets.record(j.offset, 1)
+	j.out = append(j.out, character)
+}
+
+// Writes a run of consecutive input characters to the output, noting which byte of the input
+// the run started at.
+func (j *JsonTrailingCommaStripper) emit(characters []byte, inputOffset int64) {
+	if len(characters) > 0 {
+		j.offsets.record(inputOffset, len(characters))
+		j.out = append(j.out, characters...)
+	}
+}
+
+// Maps an offset in the stripped output back to the offset of the same byte in the original
+// input, which is what you want when reporting the location of a problem to a person.
+func (j *JsonTrailingCommaStripper) OriginalOffset(offset int64) int64 {
+	return j.offsets.lookup(offset)
+}
+
+// Records where bytes were removed from a stream so that offsets in the output can be mapped
+// back to offsets in the input. Each entry marks the output offset at which the distance
+// between the output and the input changes.
+type offsetMap struct {
+	outputs []int64
+	shifts  []int64
+	output  int64
+}
+
+// Notes that the next count bytes written to the output came from consecutive bytes of the input,
+// starting at inputOffset.
+func (m *offsetMap) record(inputOffset int64, count int) {
+	shift := inputOffset - m.output
+	if len(m.shifts) == 0 || m.shifts[len(m.shifts)-1] != shift {
+		m.outputs = append(m.outputs, m.output)
+		m.shifts = append(m.shifts, shift)
+	}
+	m.output += int64(count)
+}
+
+// Returns the input offset matching the output offset. Offsets past the end of the output map
+// to just past the last byte that was written.
+func (m *offsetMap) lookup(offset int64) int64 {
+	index := sort.Search(len(m.outputs), func(i int) bool { return m.outputs[i] > offset }) - 1
+	if index < 0 {
+		return offset
+	}
+	return offset + m.shifts[index]
+}
+
+// Chains together every pre-processing stage a configuration goes through before it reaches
+// the JSON decoder.
+type preProcessor struct {
+	comments *JsonCommentStripper
+	commas   *JsonTrailingCommaStripper
+}
+
+// Creates the pre-processing stages for the reader.
+func preProcess(reader io.Reader, options *loadOptions) *preProcessor {
+	comments := NewJsonCommentStripper(reader, options.stripperOptions...)
+	return &preProcessor{comments, NewJsonTrailingCommaStripper(comments)}
+}
+
+func (p *preProcessor) Read(b []byte) (int, error) {
+	return p.commas.Read(b)
+}
+
+// Maps an offset in the fully processed output back to the original input.
+func (p *preProcessor) OriginalOffset(offset int64) int64 {
+	return p.comments.OriginalOffset(p.commas.OriginalOffset(offset))
 }
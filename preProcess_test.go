@@ -0,0 +1,169 @@
+package jsonconfig_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/callum-ramage/jsonconfig"
+)
+
+func TestBlockComments(test *testing.T) {
+	config, err := jsonconfig.LoadAbstract("./configs/BlockCommentConfig.conf", `{"test_default": "works"}`)
+
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	if config["test_string"].Str != "string value" {
+		fmt.Println(config["test_string"].Str)
+		test.Error()
+	}
+
+	if config["pl/*ace"].Str != "valid json" {
+		fmt.Println(config["pl/*ace"].Str)
+		test.Error()
+	}
+
+	if len(config["test_array"].Arr) != 2 || config["test_array.1"].Str != "array value 1" {
+		fmt.Println(config["test_array"].Arr)
+		test.Error()
+	}
+
+	if config["test_number"].Int != 5 {
+		fmt.Println(config["test_number"].Int)
+		test.Error()
+	}
+
+	if config["test_default"].Str != "works" {
+		fmt.Println(config["test_default"].Str)
+		test.Error()
+	}
+
+	if config["escaped_quote"].Str != "tes\"/*t*/" {
+		fmt.Println(config["escaped_quote"].Str)
+		test.Error()
+	}
+}
+
+func TestCommentStripperSmallReads(test *testing.T) {
+	source := `{"a": "/*x*/ \\", /* multi
+line */ "b": 1 / 2, // tail
+"c": "d"}/`
+	expected := `{"a": "/*x*/ \\",   "b": 1 / 2, 
+"c": "d"}/`
+
+	// Force every comment to span several buffer refills and reads
+	stripper := jsonconfig.NewJsonCommentStripper(iotest.OneByteReader(strings.NewReader(source)))
+	output, err := io.ReadAll(iotest.OneByteReader(stripper))
+
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+	}
+
+	if string(output) != expected {
+		fmt.Printf("%q\n", output)
+		test.Error()
+	}
+}
+
+func TestCommentStripperUnterminated(test *testing.T) {
+	_, err := jsonconfig.LoadString(`{"a": 1 /* never closed`, "")
+
+	if !errors.Is(err, jsonconfig.ErrUnterminatedComment) {
+		fmt.Println(err)
+		test.Error()
+	}
+}
+
+func TestHashComments(test *testing.T) {
+	config, err := jsonconfig.LoadAbstract("./configs/HashCommentConfig.conf", `{"test_default": 1 # in defaults too
+	}`, jsonconfig.HashComments())
+
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	if config["test_string"].Str != "string value" {
+		fmt.Println(config["test_string"].Str)
+		test.Error()
+	}
+
+	if config["test_hash"].Str != "#not a comment" {
+		fmt.Println(config["test_hash"].Str)
+		test.Error()
+	}
+
+	if config["test_number"].Int != 7 || config["test_default"].Int != 1 {
+		fmt.Println(config["test_number"].Int, config["test_default"].Int)
+		test.Error()
+	}
+
+	// Without the option a # is just an invalid character
+	if _, err = jsonconfig.LoadAbstract("./configs/HashCommentConfig.conf", ""); err == nil {
+		test.Error()
+	}
+}
+
+func TestTrailingCommas(test *testing.T) {
+	config, err := jsonconfig.LoadAbstract("./configs/TrailingCommaConfig.conf", `{"example_default": 4,}`)
+
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	if len(config["example_array"].Arr) != 2 || config["example_array.1"].Str != "a comma, inside a string," {
+		fmt.Println(config["example_array"].Arr)
+		test.Error()
+	}
+
+	if config["example_object.example_number"].Num != 5.3 || config["example_default"].Int != 4 {
+		fmt.Println(config["example_object.example_number"].Num, config["example_default"].Int)
+		test.Error()
+	}
+
+	structConfig := configuration{}
+	if err = jsonconfig.Load("./configs/TrailingCommaConfig.conf", &structConfig); err != nil {
+		fmt.Println(err)
+		test.Error()
+	}
+
+	if _, err = jsonconfig.LoadString(`{"a": [1, /* gone */ ], "b": {"c": ",]"  ,
+	},}`, ""); err != nil {
+		fmt.Println(err)
+		test.Error()
+	}
+
+	// Commas that aren't trailing still have to be valid
+	if _, err = jsonconfig.LoadString(`{"a": [1,,2]}`, ""); err == nil {
+		test.Error()
+	}
+}
+
+func TestTrailingCommaStripperSmallReads(test *testing.T) {
+	source := "[1 ,\n\t] , {\"a,\\\"\": 2,} ,"
+	expected := "[1 \n\t] , {\"a,\\\"\": 2} ,"
+
+	stripper := jsonconfig.NewJsonTrailingCommaStripper(iotest.OneByteReader(strings.NewReader(source)))
+	output, err := io.ReadAll(iotest.OneByteReader(stripper))
+
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+	}
+
+	if string(output) != expected {
+		fmt.Printf("%q\n", output)
+		test.Error()
+	}
+}
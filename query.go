@@ -0,0 +1,692 @@
+package jsonconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A value found by Query, along with its dotted path. Keys within the path are escaped with
+// EscapeKey, so config.Get(match.Path) finds the same value. The one exception is a path that
+// is shadowed by a key containing dots, such as the path a.b in {"a.b": 1, "a": {"b": 2}}, as
+// Get matches the key "a.b" before it splits the path.
+type Match struct {
+	Path  string
+	Value JSONValue
+}
+
+// Describes a query that couldn't be parsed.
+type QueryError struct {
+	Query string
+	// The byte offset within the query of the problem.
+	Offset  int
+	Message string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("jsonconfig: query %q, offset %d: %s", e.Query, e.Offset, e.Message)
+}
+
+// Finds every value in the configuration matching a JSONPath (RFC 9535) style query. The
+// leading $ may be left out, so "upstreams[*].host" and "$.upstreams[*].host" are the same.
+// The parts of a query are
+//
+//	.name or ['name']        the member with the name
+//	.* or [*]                every member of an object or element of an array
+//	[2] or [-1]              an element of an array, counting back from the end when negative
+//	[1:3] or [::2] or [::-1] a slice of an array as start:end:step, as in Python
+//	[0,2] or ['a','b']       several of the above at once
+//	..name or ..* or ..[0]   the same, applied to the value and everything within it
+//	[?expression]            members or elements for which the expression holds
+//
+// Within a filter expression @ is the member or element being tested and $ is the root of the
+// configuration. Values can be compared with ==, !=, <, <=, > and >=, and combined with &&, ||,
+// ! and parentheses. Literals are numbers written as in JSON, such as 1.5 or 2e3, 'strings',
+// "strings", true, false and null. A path on its own tests whether it exists. So the first
+// backend that is enabled is found with
+//
+//	config.Query("backends[?@.enabled == true]")
+//
+// Matches are returned in document order, with the members of an object in sorted key order.
+// Keys added by Collapse are ignored.
+func (config Configuration) Query(query string) ([]Match, error) {
+	parser := queryParser{query: query}
+	segments, err := parser.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+
+	root := JSONValue{Value: config.raw(), Obj: config}
+	return evaluateSegments(segments, []Match{{Value: root}}, root), nil
+}
+
+// One step of a query, selecting values from within each of the values matched so far.
+type querySegment struct {
+	// Whether the selectors apply to everything within each value as well as the value itself
+	recursive bool
+	selectors []querySelector
+}
+
+type selectorKind int
+
+const (
+	selectName selectorKind = iota
+	selectWildcard
+	selectIndex
+	selectSlice
+	selectFilter
+)
+
+// Picks out some of the members or elements of a value.
+type querySelector struct {
+	kind  selectorKind
+	name  string
+	index int
+	// The start, end and step of a slice, any of which may be missing
+	slice  [3]*int
+	filter *filterExpression
+}
+
+// Applies each segment in turn to the matches.
+func evaluateSegments(segments []querySegment, matches []Match, root JSONValue) []Match {
+	for _, segment := range segments {
+		var next []Match
+		for _, match := range matches {
+			candidates := []Match{match}
+			if segment.recursive {
+				candidates = descendants(match, nil)
+			}
+			for _, candidate := range candidates {
+				for _, selector := range segment.selectors {
+					next = selector.apply(candidate, root, next)
+				}
+			}
+		}
+		matches = next
+	}
+	return matches
+}
+
+// Lists the match followed by every value within it, in document order.
+func descendants(match Match, found []Match) []Match {
+	found = append(found, match)
+	for _, child := range children(match) {
+		found = descendants(child, found)
+	}
+	return found
+}
+
+// Lists the members of an object or the elements of an array.
+func children(match Match) []Match {
+	var found []Match
+	switch match.Value.Kind() {
+	case KindObject:
+		for _, key := range sortedKeys(match.Value.Obj) {
+			if !match.Value.Obj.addedByCollapse(key) {
+				found = append(found, Match{joinPath(match.Path, key), match.Value.Obj[key]})
+			}
+		}
+	case KindArray:
+		for i, element := range match.Value.Arr {
+			found = append(found, Match{joinPath(match.Path, strconv.Itoa(i)), element})
+		}
+	}
+	return found
+}
+
+// Adds whatever the selector picks from the match to found.
+func (selector querySelector) apply(match Match, root JSONValue, found []Match) []Match {
+	switch selector.kind {
+	case selectName:
+		if match.Value.Kind() == KindObject {
+			if member, ok := match.Value.Obj[selector.name]; ok && !match.Value.Obj.addedByCollapse(selector.name) {
+				found = append(found, Match{joinPath(match.Path, selector.name), member})
+			}
+		}
+	case selectWildcard:
+		found = append(found, children(match)...)
+	case selectIndex:
+		if match.Value.Kind() == KindArray {
+			if index, ok := arrayIndex(strconv.Itoa(selector.index), len(match.Value.Arr)); ok {
+				found = append(found, Match{joinPath(match.Path, strconv.Itoa(index)), match.Value.Arr[index]})
+			}
+		}
+	case selectSlice:
+		if match.Value.Kind() == KindArray {
+			for _, index := range sliceIndices(selector.slice, len(match.Value.Arr)) {
+				found = append(found, Match{joinPath(match.Path, strconv.Itoa(index)), match.Value.Arr[index]})
+			}
+		}
+	case selectFilter:
+		for _, child := range children(match) {
+			if selector.filter.test(child.Value, root) {
+				found = append(found, child)
+			}
+		}
+	}
+	return found
+}
+
+// Lists the indices a slice selects from an array of the length, in the order they're selected.
+func sliceIndices(slice [3]*int, length int) []int {
+	step := 1
+	if slice[2] != nil {
+		step = *slice[2]
+	}
+	if step == 0 {
+		return nil
+	}
+
+	normalise := func(index int) int {
+		if index < 0 {
+			return length + index
+		}
+		return index
+	}
+	clamp := func(index int, low int, high int) int {
+		if index < low {
+			return low
+		}
+		if index > high {
+			return high
+		}
+		return index
+	}
+
+	var indices []int
+	if step > 0 {
+		start, end := 0, length
+		if slice[0] != nil {
+			start = clamp(normalise(*slice[0]), 0, length)
+		}
+		if slice[1] != nil {
+			end = clamp(normalise(*slice[1]), 0, length)
+		}
+		for i := start; i < end; i += step {
+			indices = append(indices, i)
+		}
+		return indices
+	}
+
+	start, end := length-1, -1
+	if slice[0] != nil {
+		start = clamp(normalise(*slice[0]), -1, length-1)
+	}
+	if slice[1] != nil {
+		end = clamp(normalise(*slice[1]), -1, length-1)
+	}
+	for i := start; i > end; i += step {
+		indices = append(indices, i)
+	}
+	return indices
+}
+
+type filterOperator int
+
+const (
+	filterOr filterOperator = iota
+	filterAnd
+	filterNot
+	filterCompare
+	filterPath
+	filterLiteral
+)
+
+// A node of a parsed filter expression.
+type filterExpression struct {
+	operator filterOperator
+	// The comparison, such as "==" or "<="
+	comparison  string
+	left, right *filterExpression
+	// A path from @, or from $ when absolute is set
+	absolute bool
+	path     []querySegment
+	literal  interface{}
+}
+
+// Evaluates the expression as a condition on the value being filtered.
+func (e *filterExpression) test(current JSONValue, root JSONValue) bool {
+	switch e.operator {
+	case filterOr:
+		return e.left.test(current, root) || e.right.test(current, root)
+	case filterAnd:
+		return e.left.test(current, root) && e.right.test(current, root)
+	case filterNot:
+		return !e.left.test(current, root)
+	case filterCompare:
+		left, leftOk := e.left.value(current, root)
+		right, rightOk := e.right.value(current, root)
+		return compareFilterValues(e.comparison, left, leftOk, right, rightOk)
+	case filterPath:
+		return len(e.matches(current, root)) > 0
+	default:
+		// A literal on its own
+		return e.literal != nil && e.literal != false
+	}
+}
+
+// Evaluates a path or literal to a single abstract value, reporting false for a path that
+// doesn't match exactly one value.
+func (e *filterExpression) value(current JSONValue, root JSONValue) (interface{}, bool) {
+	if e.operator == filterLiteral {
+		return e.literal, true
+	}
+	matches := e.matches(current, root)
+	if len(matches) != 1 {
+		return nil, false
+	}
+	return matches[0].Value.raw(), true
+}
+
+// Finds the values a path within a filter leads to.
+func (e *filterExpression) matches(current JSONValue, root JSONValue) []Match {
+	start := current
+	if e.absolute {
+		start = root
+	}
+	return evaluateSegments(e.path, []Match{{Value: start}}, root)
+}
+
+// Compares two values found by a filter. As in RFC 9535, two missing values are equal to each
+// other, and values of different types are never equal or ordered.
+func compareFilterValues(comparison string, left interface{}, leftOk bool, right interface{}, rightOk bool) bool {
+	// Most decimals can't be held exactly in a float64, so a float64 is only compared with another
+	_, leftFloat := left.(float64)
+	_, rightFloat := right.(float64)
+	if number, ok := left.(json.Number); ok && rightFloat {
+		left, _ = number.Float64()
+	}
+	if number, ok := right.(json.Number); ok && leftFloat {
+		right, _ = number.Float64()
+	}
+
+	equal := func() bool {
+		if !leftOk || !rightOk {
+			return leftOk == rightOk
+		}
+		return equalValues(left, right)
+	}
+	less := func() bool {
+		if !leftOk || !rightOk {
+			return false
+		}
+		leftNumber, rightNumber := (JSONValue{Value: left}).Decimal(), (JSONValue{Value: right}).Decimal()
+		if leftNumber != nil && rightNumber != nil {
+			return leftNumber.Cmp(rightNumber) < 0
+		}
+		leftString, leftIsString := left.(string)
+		rightString, rightIsString := right.(string)
+		return leftIsString && rightIsString && leftString < rightString
+	}
+
+	switch comparison {
+	case "==":
+		return equal()
+	case "!=":
+		return !equal()
+	case "<":
+		return less()
+	case "<=":
+		return less() || equal()
+	case ">":
+		left, right = right, left
+		return less()
+	default:
+		left, right = right, left
+		return less() || equal()
+	}
+}
+
+// Parses the text of a query.
+type queryParser struct {
+	query string
+	pos   int
+}
+
+// Builds a QueryError for the current position.
+func (p *queryParser) errorf(format string, args ...interface{}) error {
+	return &QueryError{Query: p.query, Offset: p.pos, Message: fmt.Sprintf(format, args...)}
+}
+
+// Parses a whole query.
+func (p *queryParser) parseQuery() ([]querySegment, error) {
+	var segments []querySegment
+	if !p.consume("$") && p.pos < len(p.query) && p.query[p.pos] != '.' && p.query[p.pos] != '[' {
+		// A query can start with a name without a $ or a dot
+		name, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, querySegment{selectors: []querySelector{{kind: selectName, name: name}}})
+	}
+
+	rest, err := p.parseSegments()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.query) {
+		return nil, p.errorf("unexpected %q", p.query[p.pos])
+	}
+	return append(segments, rest...), nil
+}
+
+// Parses segments for as long as there are any.
+func (p *queryParser) parseSegments() ([]querySegment, error) {
+	var segments []querySegment
+	for {
+		var segment querySegment
+		var err error
+		switch {
+		case p.consume(".."):
+			segment.recursive = true
+			if p.pos < len(p.query) && p.query[p.pos] == '[' {
+				segment.selectors, err = p.parseBracket()
+			} else {
+				segment.selectors, err = p.parseDotted()
+			}
+		case p.consume("."):
+			segment.selectors, err = p.parseDotted()
+		case p.pos < len(p.query) && p.query[p.pos] == '[':
+			segment.selectors, err = p.parseBracket()
+		default:
+			return segments, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, segment)
+	}
+}
+
+// Parses what follows a dot, which is a name or a wildcard.
+func (p *queryParser) parseDotted() ([]querySelector, error) {
+	if p.consume("*") {
+		return []querySelector{{kind: selectWildcard}}, nil
+	}
+	name, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+	return []querySelector{{kind: selectName, name: name}}, nil
+}
+
+// The characters that end a name written without quotes.
+const queryNameStops = ".[]()=!<>&|,:?@$*'\" \t\r\n"
+
+// Parses a name written without quotes.
+func (p *queryParser) parseName() (string, error) {
+	start := p.pos
+	for p.pos < len(p.query) && !strings.ContainsRune(queryNameStops, rune(p.query[p.pos])) {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorf("expected a name")
+	}
+	return p.query[start:p.pos], nil
+}
+
+// Parses the selectors between [ and ].
+func (p *queryParser) parseBracket() ([]querySelector, error) {
+	p.pos++
+	var selectors []querySelector
+	for {
+		p.skipSpaces()
+		selector, err := p.parseSelector()
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
+
+		p.skipSpaces()
+		if p.consume("]") {
+			return selectors, nil
+		}
+		if !p.consume(",") {
+			return nil, p.errorf("expected , or ]")
+		}
+	}
+}
+
+// Parses a single selector within brackets.
+func (p *queryParser) parseSelector() (querySelector, error) {
+	switch {
+	case p.consume("*"):
+		return querySelector{kind: selectWildcard}, nil
+	case p.consume("?"):
+		p.skipSpaces()
+		filter, err := p.parseOr()
+		if err != nil {
+			return querySelector{}, err
+		}
+		return querySelector{kind: selectFilter, filter: filter}, nil
+	case p.pos < len(p.query) && (p.query[p.pos] == '\'' || p.query[p.pos] == '"'):
+		name, err := p.parseString()
+		return querySelector{kind: selectName, name: name}, err
+	}
+
+	// An index or a slice
+	var parts [3]*int
+	part := 0
+	for {
+		p.skipSpaces()
+		if p.pos < len(p.query) && (p.query[p.pos] == '-' || ('0' <= p.query[p.pos] && p.query[p.pos] <= '9')) {
+			start := p.pos
+			p.pos++
+			for p.pos < len(p.query) && '0' <= p.query[p.pos] && p.query[p.pos] <= '9' {
+				p.pos++
+			}
+			number, err := strconv.Atoi(p.query[start:p.pos])
+			if err != nil {
+				p.pos = start
+				return querySelector{}, p.errorf("invalid index")
+			}
+			parts[part] = &number
+		}
+		p.skipSpaces()
+		if part == 2 || !p.consume(":") {
+			break
+		}
+		part++
+	}
+
+	if part == 0 {
+		if parts[0] == nil {
+			return querySelector{}, p.errorf("expected a selector")
+		}
+		return querySelector{kind: selectIndex, index: *parts[0]}, nil
+	}
+	return querySelector{kind: selectSlice, slice: parts}, nil
+}
+
+// Parses a quoted string, which may use either kind of quote.
+func (p *queryParser) parseString() (string, error) {
+	quote := p.query[p.pos]
+	start := p.pos
+	p.pos++
+
+	var builder strings.Builder
+	for p.pos < len(p.query) {
+		character := p.query[p.pos]
+		switch {
+		case character == quote:
+			p.pos++
+			return builder.String(), nil
+		case character == '\\' && p.pos+1 < len(p.query):
+			p.pos++
+			switch escaped := p.query[p.pos]; escaped {
+			case 'n':
+				builder.WriteByte('\n')
+			case 't':
+				builder.WriteByte('\t')
+			default:
+				builder.WriteByte(escaped)
+			}
+		default:
+			builder.WriteByte(character)
+		}
+		p.pos++
+	}
+	p.pos = start
+	return "", p.errorf("unterminated string")
+}
+
+// Parses expressions joined by ||.
+func (p *queryParser) parseOr() (*filterExpression, error) {
+	left, err := p.parseAnd()
+	for err == nil {
+		p.skipSpaces()
+		if !p.consume("||") {
+			return left, nil
+		}
+		var right *filterExpression
+		if right, err = p.parseAnd(); err == nil {
+			left = &filterExpression{operator: filterOr, left: left, right: right}
+		}
+	}
+	return nil, err
+}
+
+// Parses expressions joined by &&.
+func (p *queryParser) parseAnd() (*filterExpression, error) {
+	left, err := p.parseUnary()
+	for err == nil {
+		p.skipSpaces()
+		if !p.consume("&&") {
+			return left, nil
+		}
+		var right *filterExpression
+		if right, err = p.parseUnary(); err == nil {
+			left = &filterExpression{operator: filterAnd, left: left, right: right}
+		}
+	}
+	return nil, err
+}
+
+// Parses a negation or a comparison.
+func (p *queryParser) parseUnary() (*filterExpression, error) {
+	p.skipSpaces()
+	if p.pos+1 < len(p.query) && p.query[p.pos] == '!' && p.query[p.pos+1] != '=' {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &filterExpression{operator: filterNot, left: operand}, nil
+	}
+
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	for _, comparison := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(comparison) {
+			right, err := p.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+			return &filterExpression{operator: filterCompare, comparison: comparison, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+// Parses a parenthesised expression, a path or a literal.
+func (p *queryParser) parsePrimary() (*filterExpression, error) {
+	p.skipSpaces()
+	if p.pos == len(p.query) {
+		return nil, p.errorf("unexpected end of query")
+	}
+
+	switch character := p.query[p.pos]; {
+	case character == '(':
+		p.pos++
+		expression, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if !p.consume(")") {
+			return nil, p.errorf("expected )")
+		}
+		return expression, nil
+	case character == '@' || character == '$':
+		p.pos++
+		path, err := p.parseSegments()
+		if err != nil {
+			return nil, err
+		}
+		return &filterExpression{operator: filterPath, absolute: character == '$', path: path}, nil
+	case character == '\'' || character == '"':
+		text, err := p.parseString()
+		return &filterExpression{operator: filterLiteral, literal: text}, err
+	}
+
+	if number := p.scanNumber(); number != "" {
+		return &filterExpression{operator: filterLiteral, literal: json.Number(number)}, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.query) && !strings.ContainsRune(queryNameStops, rune(p.query[p.pos])) {
+		p.pos++
+	}
+	switch p.query[start:p.pos] {
+	case "true":
+		return &filterExpression{operator: filterLiteral, literal: true}, nil
+	case "false":
+		return &filterExpression{operator: filterLiteral, literal: false}, nil
+	case "null":
+		return &filterExpression{operator: filterLiteral, literal: nil}, nil
+	}
+	p.pos = start
+	return nil, p.errorf("expected a path or a literal")
+}
+
+// Moves past a number written as it would be in JSON, such as -1.5e3, returning its text. Returns
+// "" and stays put if there isn't one.
+func (p *queryParser) scanNumber() string {
+	start := p.pos
+	digits := func() int {
+		count := 0
+		for p.pos < len(p.query) && '0' <= p.query[p.pos] && p.query[p.pos] <= '9' {
+			p.pos++
+			count++
+		}
+		return count
+	}
+
+	p.consume("-")
+	if digits() == 0 {
+		p.pos = start
+		return ""
+	}
+	if fraction := p.pos; p.consume(".") && digits() == 0 {
+		p.pos = fraction
+	}
+	if exponent := p.pos; p.consume("e") || p.consume("E") {
+		if !p.consume("+") {
+			p.consume("-")
+		}
+		if digits() == 0 {
+			p.pos = exponent
+		}
+	}
+	return p.query[start:p.pos]
+}
+
+// Skips any whitespace.
+func (p *queryParser) skipSpaces() {
+	for p.pos < len(p.query) && strings.IndexByte(" \t\r\n", p.query[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// Moves past text if it comes next, reporting whether it did.
+func (p *queryParser) consume(text string) bool {
+	if strings.HasPrefix(p.query[p.pos:], text) {
+		p.pos += len(text)
+		return true
+	}
+	return false
+}
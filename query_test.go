@@ -0,0 +1,147 @@
+package jsonconfig_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/callum-ramage/jsonconfig"
+)
+
+// Lists the paths of the matches, separated by spaces.
+func matchPaths(matches []jsonconfig.Match) string {
+	paths := make([]string, len(matches))
+	for i, match := range matches {
+		paths[i] = match.Path
+	}
+	return strings.Join(paths, " ")
+}
+
+func TestQuery(test *testing.T) {
+	config, err := jsonconfig.LoadAbstractNoCollapse("./configs/query/Service.conf", "")
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	cases := map[string]string{
+		"name":                                 "name",
+		"$.tls.cert":                           "tls.cert",
+		"$['tls'][\"host\"]":                   "tls.host",
+		"upstreams[*].host":                    "upstreams.0.host upstreams.1.host upstreams.2.host",
+		"upstreams.*.port":                     "upstreams.0.port upstreams.1.port upstreams.2.port",
+		"upstreams[-1].host":                   "upstreams.2.host",
+		"upstreams[0,2].port":                  "upstreams.0.port upstreams.2.port",
+		"upstreams[1:].host":                   "upstreams.1.host upstreams.2.host",
+		"upstreams[::-2].host":                 "upstreams.2.host upstreams.0.host",
+		"upstreams[5]":                         "",
+		"$..host":                              "tls.host upstreams.0.host upstreams.1.host upstreams.2.host",
+		"tls.*":                                "tls.cert tls.host",
+		"backends[?@.enabled == true].name":    "backends.1.name backends.2.name",
+		"backends[?!(@.enabled == true)].name": "backends.0.name",
+		"upstreams[?@.weight].host":            "upstreams.0.host upstreams.1.host",
+		"upstreams[?@.port >= 8081 && @.port < 9000]": "upstreams.1",
+		"upstreams[?@.weight > 2 || @.port == 9090]":  "upstreams.0 upstreams.2",
+		"upstreams[?@.host == $.upstreams[1].host]":   "upstreams.1",
+		"backends[?@.name != 'primary'].name":         "backends.1.name backends.2.name",
+		"missing[*]":                                  "",
+		"upstreams[?@.latency < 1.1].host":            "upstreams.0.host",
+		"upstreams[?@.latency <= 1.1].host":           "upstreams.0.host upstreams.1.host",
+		"upstreams[?@.latency == 0.25].host":          "upstreams.0.host",
+		"upstreams[?@.latency == 1.1].host":           "upstreams.1.host",
+		"upstreams[?@.latency == 2.5E+2].host":        "upstreams.2.host",
+		"upstreams[?@.latency > 1e2].host":            "upstreams.2.host",
+		"upstreams[?@.latency > -1.5e-3].host":        "upstreams.0.host upstreams.1.host upstreams.2.host",
+	}
+	for query, expected := range cases {
+		matches, err := config.Query(query)
+		if err != nil {
+			fmt.Println(query, err)
+			test.Error()
+			continue
+		}
+		if matchPaths(matches) != expected {
+			fmt.Printf("%s: %q, expected %q\n", query, matchPaths(matches), expected)
+			test.Error()
+		}
+	}
+
+	// Exact numbers compare the same way
+	precise, _ := jsonconfig.LoadAbstractNoCollapse("./configs/query/Service.conf", "", jsonconfig.PreciseNumbers())
+	for _, query := range []string{"upstreams[?@.latency == 0.25].host", "upstreams[?@.latency < 1.1].host", "upstreams[?@.latency != 1.1 && @.latency < 2].host"} {
+		if matches, _ := precise.Query(query); matchPaths(matches) != "upstreams.0.host" {
+			fmt.Println(query, matchPaths(matches))
+			test.Error()
+		}
+	}
+
+	matches, _ := config.Query("backends[?@.enabled == true]")
+	if len(matches) == 0 || matches[0].Value.Obj["name"].Str != "secondary" {
+		fmt.Println(matches)
+		test.Error()
+	}
+}
+
+func TestQueryPathsWorkWithGet(test *testing.T) {
+	for _, filename := range []string{"./configs/query/Service.conf", "./configs/query/Collisions.conf"} {
+		config, _ := jsonconfig.LoadAbstract(filename, `{"dotted.key": {"value": 1}}`)
+
+		matches, err := config.Query("$..*")
+		if err != nil || len(matches) == 0 {
+			fmt.Println(err)
+			test.Error()
+			continue
+		}
+		for _, match := range matches {
+			got, _ := config.Get(match.Path).MarshalJSON()
+			expected, _ := match.Value.MarshalJSON()
+			if string(got) != string(expected) {
+				fmt.Printf("%s: got %s, expected %s\n", match.Path, got, expected)
+				test.Error()
+			}
+
+			// Keys added by Collapse aren't matched
+			if match.Path == "tls\\.cert" || match.Path == "upstreams\\.0" || match.Path == "a\\.b\\.c\\.d\\.e" {
+				fmt.Println(match.Path)
+				test.Error()
+			}
+		}
+	}
+
+	config, _ := jsonconfig.LoadAbstract("./configs/query/Collisions.conf", "")
+	matches, _ := config.Query("$['a.b'].c")
+	if matchPaths(matches) != "a\\.b.c" || matches[0].Value.Num != 2 {
+		fmt.Println(matchPaths(matches))
+		test.Error()
+	}
+}
+
+func TestQueryErrors(test *testing.T) {
+	config, _ := jsonconfig.LoadString(`{"a": [1, 2]}`, "")
+
+	for _, query := range []string{"a[", "a[?]", "a[?@ ==]", "a['b", "a.", "a[1 2]", "a[?(@ > 1]"} {
+		_, err := config.Query(query)
+		var queryErr *jsonconfig.QueryError
+		if !errors.As(err, &queryErr) || !strings.HasPrefix(err.Error(), "jsonconfig: ") {
+			fmt.Println(query, err)
+			test.Error()
+		}
+	}
+}
+
+func ExampleConfiguration_Query() {
+	config, _ := jsonconfig.LoadString(`{
+		"upstreams": [
+			{"host": "alpha.internal", "enabled": true},
+			{"host": "beta.internal", "enabled": false}
+		]
+	}`, "")
+
+	matches, _ := config.Query("upstreams[?@.enabled == true].host")
+	for _, match := range matches {
+		fmt.Println(match.Path, match.Value.Str)
+	}
+	// Output: upstreams.0.host alpha.internal
+}
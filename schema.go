@@ -0,0 +1,619 @@
+package jsonconfig
+
+import (
+	"fmt"
+	"math/big"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Describes a single way in which a configuration doesn't match a Schema.
+type ValidationError struct {
+	// The dotted path of the offending value, or "" for the configuration as a whole.
+	Path string
+	// The schema keyword that was broken, such as "type" or "required".
+	Keyword string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("jsonconfig: %s", e.Message)
+	}
+	return fmt.Sprintf("jsonconfig: %s: %s", e.Path, e.Message)
+}
+
+// Every way in which a configuration doesn't match a Schema, ordered by where they occur.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	return joinErrors(e)
+}
+
+// Notes a violation at the path.
+func (e *ValidationErrors) add(path string, keyword string, format string, arguments ...interface{}) {
+	*e = append(*e, &ValidationError{Path: path, Keyword: keyword, Message: fmt.Sprintf(format, arguments...)})
+}
+
+// A JSON Schema (https://json-schema.org) that configurations can be checked against. The
+// keywords understood are a subset of draft 2020-12:
+//
+//	type                                   a type name or a list of them
+//	enum, const                            the allowed values
+//	minimum, maximum                       inclusive bounds on a number
+//	exclusiveMinimum, exclusiveMaximum     exclusive bounds on a number
+//	minLength, maxLength, pattern          limits on a string
+//	items, prefixItems, minItems, maxItems limits on an array
+//	properties, required                   the members of an object
+//	additionalProperties                   false, or a schema for members not in properties
+//	$ref, $defs                            references within the same document, such as "#/$defs/port"
+//
+// Any other keyword is ignored, as the specification requires.
+type Schema struct {
+	root *schemaNode
+}
+
+// A single compiled schema within a Schema document.
+type schemaNode struct {
+	// Set for the boolean schemas true and false
+	always *bool
+
+	ref       string
+	reference *schemaNode
+
+	types []string
+	enum  []interface{}
+	// Held as a single element slice, as the constant may itself be null
+	constant []interface{}
+
+	minimum          interface{}
+	maximum          interface{}
+	exclusiveMinimum interface{}
+	exclusiveMaximum interface{}
+
+	minLength int
+	maxLength int
+	pattern   *regexp.Regexp
+
+	items       *schemaNode
+	prefixItems []*schemaNode
+	minItems    int
+	maxItems    int
+
+	properties           map[string]*schemaNode
+	required             []string
+	additionalProperties *schemaNode
+}
+
+// Loads a schema from a file. Like a configuration the file may contain comments and trailing commas.
+func LoadSchema(filename string) (*Schema, error) {
+	source, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return parseSchema(filename, source)
+}
+
+// Parses a schema from a string. Like a configuration the string may contain comments and trailing commas.
+func ParseSchema(schema string) (*Schema, error) {
+	return parseSchema("", []byte(schema))
+}
+
+// Parses and compiles a schema document.
+func parseSchema(filename string, source []byte) (*Schema, error) {
+	document, err := parseSource(filename, source, newLoadOptions([]LoadOption{PreciseNumbers()}))
+	if err != nil {
+		return nil, err
+	}
+
+	compiler := schemaCompiler{document: document, nodes: map[string]*schemaNode{}}
+	root, err := compiler.compile(document, "")
+	if err != nil {
+		return nil, err
+	}
+	if err = compiler.resolveReferences(); err != nil {
+		return nil, err
+	}
+	return &Schema{root}, nil
+}
+
+// Turns the abstract values of a schema document into schemaNodes.
+type schemaCompiler struct {
+	document interface{}
+	// Every node compiled so far, by its JSON pointer within the document
+	nodes map[string]*schemaNode
+	// Nodes whose $ref is yet to be resolved
+	unresolved []*schemaNode
+}
+
+// Describes a problem with the schema found at the JSON pointer.
+func schemaError(pointer string, format string, arguments ...interface{}) error {
+	return fmt.Errorf("jsonconfig: invalid schema at #%s: %s", pointer, fmt.Sprintf(format, arguments...))
+}
+
+// Compiles the schema found at the JSON pointer.
+func (c *schemaCompiler) compile(raw interface{}, pointer string) (*schemaNode, error) {
+	if node, ok := c.nodes[pointer]; ok {
+		return node, nil
+	}
+
+	node := &schemaNode{minLength: -1, maxLength: -1, minItems: -1, maxItems: -1}
+	c.nodes[pointer] = node
+
+	if always, ok := raw.(bool); ok {
+		node.always = &always
+		return node, nil
+	}
+	object, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, schemaError(pointer, "a schema must be an object or a bool")
+	}
+
+	var err error
+	for _, keyword := range sortedMapKeys(object) {
+		value := object[keyword]
+		keywordPointer := pointer + "/" + escapePointer(keyword)
+		switch keyword {
+		case "$ref":
+			ref, ok := value.(string)
+			if !ok {
+				return nil, schemaError(keywordPointer, "must be a string")
+			}
+			node.ref = ref
+			c.unresolved = append(c.unresolved, node)
+		case "$defs", "definitions":
+			definitions, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, schemaError(keywordPointer, "must be an object")
+			}
+			for _, name := range sortedMapKeys(definitions) {
+				if _, err = c.compile(definitions[name], keywordPointer+"/"+escapePointer(name)); err != nil {
+					return nil, err
+				}
+			}
+		case "type":
+			node.types, err = compileTypes(value, keywordPointer)
+		case "enum":
+			enum, ok := value.([]interface{})
+			if !ok {
+				return nil, schemaError(keywordPointer, "must be an array")
+			}
+			node.enum = enum
+		case "const":
+			node.constant = []interface{}{value}
+		case "minimum":
+			node.minimum, err = compileNumber(value, keywordPointer)
+		case "maximum":
+			node.maximum, err = compileNumber(value, keywordPointer)
+		case "exclusiveMinimum":
+			node.exclusiveMinimum, err = compileNumber(value, keywordPointer)
+		case "exclusiveMaximum":
+			node.exclusiveMaximum, err = compileNumber(value, keywordPointer)
+		case "minLength":
+			node.minLength, err = compileCount(value, keywordPointer)
+		case "maxLength":
+			node.maxLength, err = compileCount(value, keywordPointer)
+		case "minItems":
+			node.minItems, err = compileCount(value, keywordPointer)
+		case "maxItems":
+			node.maxItems, err = compileCount(value, keywordPointer)
+		case "pattern":
+			pattern, ok := value.(string)
+			if !ok {
+				return nil, schemaError(keywordPointer, "must be a string")
+			}
+			if node.pattern, err = regexp.Compile(pattern); err != nil {
+				return nil, schemaError(keywordPointer, "%v", err)
+			}
+		case "items":
+			node.items, err = c.compile(value, keywordPointer)
+		case "prefixItems":
+			schemas, ok := value.([]interface{})
+			if !ok {
+				return nil, schemaError(keywordPointer, "must be an array")
+			}
+			node.prefixItems = make([]*schemaNode, len(schemas))
+			for i, schema := range schemas {
+				if node.prefixItems[i], err = c.compile(schema, keywordPointer+"/"+strconv.Itoa(i)); err != nil {
+					return nil, err
+				}
+			}
+		case "properties":
+			properties, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, schemaError(keywordPointer, "must be an object")
+			}
+			node.properties = make(map[string]*schemaNode, len(properties))
+			for _, name := range sortedMapKeys(properties) {
+				if node.properties[name], err = c.compile(properties[name], keywordPointer+"/"+escapePointer(name)); err != nil {
+					return nil, err
+				}
+			}
+		case "required":
+			names, ok := value.([]interface{})
+			if !ok {
+				return nil, schemaError(keywordPointer, "must be an array of strings")
+			}
+			for _, name := range names {
+				name, ok := name.(string)
+				if !ok {
+					return nil, schemaError(keywordPointer, "must be an array of strings")
+				}
+				node.required = append(node.required, name)
+			}
+		case "additionalProperties":
+			node.additionalProperties, err = c.compile(value, keywordPointer)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+// Points every $ref at the node it refers to, compiling any that weren't reached while
+// compiling the document.
+func (c *schemaCompiler) resolveReferences() error {
+	// Resolving a reference may compile more nodes with references of their own
+	for i := 0; i < len(c.unresolved); i++ {
+		node := c.unresolved[i]
+		pointer, err := refPointer(node.ref)
+		if err != nil {
+			return err
+		}
+
+		raw, ok := lookupPointer(c.document, pointer)
+		if !ok {
+			return fmt.Errorf("jsonconfig: invalid schema: $ref %q doesn't exist", node.ref)
+		}
+		if node.reference, err = c.compile(raw, pointer); err != nil {
+			return err
+		}
+	}
+
+	// A chain of references that leads back to itself would never finish validating
+	for _, node := range c.unresolved {
+		seen := map[*schemaNode]bool{}
+		for next := node; next.reference != nil; next = next.reference {
+			if seen[next] {
+				return fmt.Errorf("jsonconfig: invalid schema: $ref %q refers back to itself", node.ref)
+			}
+			seen[next] = true
+		}
+	}
+	return nil
+}
+
+// Turns a $ref into a JSON pointer within the same document. Only references starting with #
+// are supported.
+func refPointer(ref string) (string, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return "", fmt.Errorf("jsonconfig: invalid schema: $ref %q isn't within the same document", ref)
+	}
+	pointer, err := url.PathUnescape(ref[1:])
+	if err != nil {
+		return "", fmt.Errorf("jsonconfig: invalid schema: $ref %q: %v", ref, err)
+	}
+	if pointer != "" && !strings.HasPrefix(pointer, "/") {
+		return "", fmt.Errorf("jsonconfig: invalid schema: $ref %q isn't a JSON pointer", ref)
+	}
+	return pointer, nil
+}
+
+// Finds the value at the JSON pointer within the document.
+func lookupPointer(document interface{}, pointer string) (interface{}, bool) {
+	if pointer == "" {
+		return document, true
+	}
+	value := document
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch typedValue := value.(type) {
+		case map[string]interface{}:
+			member, ok := typedValue[token]
+			if !ok {
+				return nil, false
+			}
+			value = member
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(typedValue) {
+				return nil, false
+			}
+			value = typedValue[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// Escapes a key for use as a token in a JSON pointer.
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+// Returns the keys of an abstract object in order.
+func sortedMapKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// The type names a schema can use.
+var schemaTypes = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true, "number": true, "integer": true, "string": true,
+}
+
+// Reads the value of a type keyword, which is a single type name or an array of them.
+func compileTypes(value interface{}, pointer string) ([]string, error) {
+	var names []interface{}
+	switch typedValue := value.(type) {
+	case string:
+		names = []interface{}{typedValue}
+	case []interface{}:
+		names = typedValue
+	default:
+		return nil, schemaError(pointer, "must be a string or an array of strings")
+	}
+
+	types := make([]string, len(names))
+	for i, name := range names {
+		name, ok := name.(string)
+		if !ok || !schemaTypes[name] {
+			return nil, schemaError(pointer, "%s isn't a type", renderValue(names[i]))
+		}
+		types[i] = name
+	}
+	return types, nil
+}
+
+// Checks that the value of a keyword is a number.
+func compileNumber(value interface{}, pointer string) (interface{}, error) {
+	if (JSONValue{Value: value}).Decimal() == nil {
+		return nil, schemaError(pointer, "must be a number")
+	}
+	return value, nil
+}
+
+// Reads the value of a keyword that must be a non-negative integer.
+func compileCount(value interface{}, pointer string) (int, error) {
+	decimal := JSONValue{Value: value}.Decimal()
+	if decimal == nil || !decimal.IsInt() || decimal.Sign() < 0 || !decimal.Num().IsInt64() {
+		return 0, schemaError(pointer, "must be a non-negative integer")
+	}
+	return int(decimal.Num().Int64()), nil
+}
+
+// Checks the configuration against the schema, returning every violation as ValidationErrors,
+// or nil if there aren't any. Keys added by Collapse are ignored.
+//
+//	schema, err := jsonconfig.LoadSchema("./configs/service.schema.json")
+//	...
+//	if err := schema.Validate(config); err != nil {
+//	  fmt.Println(err)
+//	}
+func (schema *Schema) Validate(config Configuration) error {
+	var errs ValidationErrors
+	schema.root.validate(config.raw(), "", &errs)
+	return errorsOrNil(errs)
+}
+
+// Checks a single abstract value against the schema, adding any violations to errs.
+func (node *schemaNode) validate(value interface{}, path string, errs *ValidationErrors) {
+	if node.always != nil {
+		if !*node.always {
+			errs.add(path, "false", "is not allowed")
+		}
+		return
+	}
+
+	if node.reference != nil {
+		node.reference.validate(value, path, errs)
+	}
+
+	if len(node.types) > 0 && !matchesType(value, node.types) {
+		errs.add(path, "type", "must be %s, not %s", describeTypes(node.types), typeName(value))
+		// The remaining keywords would only repeat the problem
+		return
+	}
+
+	if node.enum != nil && !containsValue(node.enum, value) {
+		rendered := make([]string, len(node.enum))
+		for i, allowed := range node.enum {
+			rendered[i] = renderValue(allowed)
+		}
+		errs.add(path, "enum", "must be one of %s", strings.Join(rendered, ", "))
+	}
+	if node.constant != nil && !equalValues(node.constant[0], value) {
+		errs.add(path, "const", "must be %s", renderValue(node.constant[0]))
+	}
+
+	switch typedValue := value.(type) {
+	case string:
+		node.validateString(typedValue, path, errs)
+	case []interface{}:
+		node.validateArray(typedValue, path, errs)
+	case map[string]interface{}:
+		node.validateObject(typedValue, path, errs)
+	default:
+		if number := (JSONValue{Value: value}).Decimal(); number != nil {
+			node.validateNumber(number, path, errs)
+		}
+	}
+}
+
+// Checks the bounds on a number.
+func (node *schemaNode) validateNumber(number *big.Rat, path string, errs *ValidationErrors) {
+	if node.minimum != nil && number.Cmp(JSONValue{Value: node.minimum}.Decimal()) < 0 {
+		errs.add(path, "minimum", "must be at least %s", renderValue(node.minimum))
+	}
+	if node.maximum != nil && number.Cmp(JSONValue{Value: node.maximum}.Decimal()) > 0 {
+		errs.add(path, "maximum", "must be at most %s", renderValue(node.maximum))
+	}
+	if node.exclusiveMinimum != nil && number.Cmp(JSONValue{Value: node.exclusiveMinimum}.Decimal()) <= 0 {
+		errs.add(path, "exclusiveMinimum", "must be greater than %s", renderValue(node.exclusiveMinimum))
+	}
+	if node.exclusiveMaximum != nil && number.Cmp(JSONValue{Value: node.exclusiveMaximum}.Decimal()) >= 0 {
+		errs.add(path, "exclusiveMaximum", "must be less than %s", renderValue(node.exclusiveMaximum))
+	}
+}
+
+// Checks the length and pattern of a string. Length is counted in characters rather than bytes.
+func (node *schemaNode) validateString(value string, path string, errs *ValidationErrors) {
+	length := utf8.RuneCountInString(value)
+	if node.minLength >= 0 && length < node.minLength {
+		errs.add(path, "minLength", "must be at least %d characters long", node.minLength)
+	}
+	if node.maxLength >= 0 && length > node.maxLength {
+		errs.add(path, "maxLength", "must be at most %d characters long", node.maxLength)
+	}
+	if node.pattern != nil && !node.pattern.MatchString(value) {
+		errs.add(path, "pattern", "must match %s", node.pattern)
+	}
+}
+
+// Checks the length of an array and each of its elements.
+func (node *schemaNode) validateArray(array []interface{}, path string, errs *ValidationErrors) {
+	if node.minItems >= 0 && len(array) < node.minItems {
+		errs.add(path, "minItems", "must have at least %d elements", node.minItems)
+	}
+	if node.maxItems >= 0 && len(array) > node.maxItems {
+		errs.add(path, "maxItems", "must have at most %d elements", node.maxItems)
+	}
+
+	for i, element := range array {
+		elementPath := joinPath(path, strconv.Itoa(i))
+		if i < len(node.prefixItems) {
+			node.prefixItems[i].validate(element, elementPath, errs)
+		} else if node.items != nil {
+			node.items.validate(element, elementPath, errs)
+		}
+	}
+}
+
+// Checks the members of an object.
+func (node *schemaNode) validateObject(object map[string]interface{}, path string, errs *ValidationErrors) {
+	for _, name := range node.required {
+		if _, ok := object[name]; !ok {
+			errs.add(joinPath(path, name), "required", "is required")
+		}
+	}
+
+	for _, key := range sortedMapKeys(object) {
+		memberPath := joinPath(path, key)
+		if property, ok := node.properties[key]; ok {
+			property.validate(object[key], memberPath, errs)
+		} else if node.additionalProperties != nil {
+			if node.additionalProperties.always != nil && !*node.additionalProperties.always {
+				errs.add(memberPath, "additionalProperties", "is not allowed")
+			} else {
+				node.additionalProperties.validate(object[key], memberPath, errs)
+			}
+		}
+	}
+}
+
+// Checks if the value is any of the named types.
+func matchesType(value interface{}, types []string) bool {
+	actual := typeName(value)
+	for _, name := range types {
+		if name == actual || (name == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// Names the type of an abstract value in the way a schema does. Numbers without a fractional
+// part are integers.
+func typeName(value interface{}) string {
+	switch (JSONValue{Value: value}).Kind() {
+	case KindBool:
+		return "boolean"
+	case KindNumber:
+		if decimal := (JSONValue{Value: value}).Decimal(); decimal != nil && decimal.IsInt() {
+			return "integer"
+		}
+		return "number"
+	case KindString:
+		return "string"
+	case KindArray:
+		return "array"
+	case KindObject:
+		return "object"
+	default:
+		return "null"
+	}
+}
+
+// Lists the type names for a person, such as "a string or null".
+func describeTypes(types []string) string {
+	described := make([]string, len(types))
+	for i, name := range types {
+		switch name {
+		case "null":
+			described[i] = name
+		case "array", "integer", "object":
+			described[i] = "an " + name
+		default:
+			described[i] = "a " + name
+		}
+	}
+	return strings.Join(described, " or ")
+}
+
+// Checks if any of the values equal value.
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, candidate := range values {
+		if equalValues(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks if two abstract values are equal in the way JSON Schema defines it, which compares
+// numbers by their value, so 1 and 1.0 are equal.
+func equalValues(a interface{}, b interface{}) bool {
+	aDecimal, bDecimal := JSONValue{Value: a}.Decimal(), JSONValue{Value: b}.Decimal()
+	if aDecimal != nil || bDecimal != nil {
+		return aDecimal != nil && bDecimal != nil && aDecimal.Cmp(bDecimal) == 0
+	}
+
+	switch typedA := a.(type) {
+	case []interface{}:
+		typedB, ok := b.([]interface{})
+		if !ok || len(typedA) != len(typedB) {
+			return false
+		}
+		for i := range typedA {
+			if !equalValues(typedA[i], typedB[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		typedB, ok := b.(map[string]interface{})
+		if !ok || len(typedA) != len(typedB) {
+			return false
+		}
+		for key, value := range typedA {
+			other, ok := typedB[key]
+			if !ok || !equalValues(value, other) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
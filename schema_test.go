@@ -0,0 +1,158 @@
+package jsonconfig_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/callum-ramage/jsonconfig"
+)
+
+func TestValidateSchema(test *testing.T) {
+	schema, err := jsonconfig.LoadSchema("./configs/schema/Service.schema.json")
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	config, err := jsonconfig.LoadAbstract("./configs/schema/Valid.conf", "")
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+	if err = schema.Validate(config); err != nil {
+		fmt.Println(err)
+		test.Error()
+	}
+}
+
+func TestValidateSchemaReportsEveryViolation(test *testing.T) {
+	schema, err := jsonconfig.LoadSchema("./configs/schema/Service.schema.json")
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	_, err = jsonconfig.LoadAbstract("./configs/schema/Invalid.conf", "", jsonconfig.ValidateSchema(schema))
+
+	var violations jsonconfig.ValidationErrors
+	if !errors.As(err, &violations) {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	expected := []string{
+		"level: must be one of \"debug\", \"info\", \"warn\", \"error\"",
+		"name: must match ^[a-z][a-z0-9-]*$",
+		"owner: must be a string, not integer",
+		"server.host: must be at least 1 characters long",
+		"server.port: must be at most 65535",
+		"server.tls: is not allowed",
+		"timeout: must be greater than 0",
+		"upstreams.0.host: is required",
+		"upstreams.0.port: must be at least 1",
+		"upstreams.1: must be an object, not string",
+	}
+	if len(violations) != len(expected) {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+	for i, violation := range violations {
+		if violation.Error() != "jsonconfig: "+expected[i] {
+			fmt.Println(violation)
+			test.Error()
+		}
+	}
+}
+
+func TestValidateSchemaOnLoad(test *testing.T) {
+	schema, _ := jsonconfig.LoadSchema("./configs/schema/Service.schema.json")
+
+	type service struct {
+		Name   string
+		Server struct {
+			Port int
+		}
+	}
+
+	var valid service
+	if err := jsonconfig.Load("./configs/schema/Valid.conf", &valid, jsonconfig.ValidateSchema(schema)); err != nil || valid.Server.Port != 8080 {
+		fmt.Println(err, valid)
+		test.Error()
+	}
+
+	var invalid service
+	err := jsonconfig.Load("./configs/schema/Invalid.conf", &invalid, jsonconfig.ValidateSchema(schema))
+	if violations, ok := err.(jsonconfig.ValidationErrors); !ok || len(violations) != 10 {
+		fmt.Println(err)
+		test.Error()
+	}
+}
+
+func TestSchemaKeywords(test *testing.T) {
+	tests := []struct {
+		schema string
+		config string
+		errors []string
+	}{
+		{`{"properties": {"a": {"const": 1}}}`, `{"a": 1.0}`, nil},
+		{`{"properties": {"a": {"const": null}}}`, `{"a": false}`, []string{"a: must be null"}},
+		{`{"properties": {"a": {"type": "integer"}}}`, `{"a": 1.5}`, []string{"a: must be an integer, not number"}},
+		{`{"properties": {"a": {"maxLength": 2}}}`, `{"a": "éé"}`, nil},
+		{`{"properties": {"a": {"maxItems": 1, "prefixItems": [{"type": "string"}], "items": false}}}`,
+			`{"a": ["x", 2]}`, []string{"a: must have at most 1 elements", "a.1: is not allowed"}},
+		{`{"properties": {"a": {"exclusiveMaximum": 10, "maximum": 5}}}`, `{"a": 10}`,
+			[]string{"a: must be at most 5", "a: must be less than 10"}},
+		{`{"required": ["a", "b"]}`, `{}`, []string{"a: is required", "b: is required"}},
+		{`{"type": "array"}`, `{}`, []string{"must be an array, not object"}},
+		{`{"$ref": "#/definitions/a~1b", "definitions": {"a/b": {"required": ["c"]}}}`, `{}`, []string{"c: is required"}},
+		{`{"properties": {"a": {"$ref": "#"}}, "required": ["b"]}`, `{"a": {"b": 1}}`, []string{"b: is required"}},
+	}
+
+	for _, testCase := range tests {
+		schema, err := jsonconfig.ParseSchema(testCase.schema)
+		if err != nil {
+			fmt.Println(testCase.schema, err)
+			test.Error()
+			continue
+		}
+		config, _ := jsonconfig.LoadString(testCase.config, "")
+
+		var messages []string
+		if err = schema.Validate(config); err != nil {
+			for _, violation := range err.(jsonconfig.ValidationErrors) {
+				messages = append(messages, strings.TrimPrefix(violation.Error(), "jsonconfig: "))
+			}
+		}
+		if strings.Join(messages, "\n") != strings.Join(testCase.errors, "\n") {
+			fmt.Println(testCase.schema, messages)
+			test.Error()
+		}
+	}
+}
+
+func TestInvalidSchema(test *testing.T) {
+	schemas := []string{
+		`{"type": "text"}`,
+		`{"pattern": "("}`,
+		`{"minLength": -1}`,
+		`{"$ref": "#/$defs/missing"}`,
+		`{"$ref": "other.json#/a"}`,
+		`{"$ref": "#/$defs/a", "$defs": {"a": {"$ref": "#/$defs/b"}, "b": {"$ref": "#/$defs/a"}}}`,
+		`{"properties": {"a": 5}}`,
+		`{"type": "object"`,
+	}
+
+	for _, schema := range schemas {
+		if _, err := jsonconfig.ParseSchema(schema); err == nil {
+			fmt.Println(schema)
+			test.Error()
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package jsonconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Describes a key that a Strict load doesn't recognise, such as a misspelling.
+type UnknownKeyError struct {
+	// The dotted path of the key.
+	Path string
+	// The file containing the key, or empty when loading a string.
+	Filename string
+	// The line and column of the key, both counting from 1.
+	Line   int
+	Column int
+}
+
+func (e *UnknownKeyError) Error() string {
+	if e.Filename == "" {
+		return fmt.Sprintf("jsonconfig: line %d, column %d: unknown key %q", e.Line, e.Column, e.Path)
+	}
+	return fmt.Sprintf("%s:%d:%d: unknown key %q", e.Filename, e.Line, e.Column, e.Path)
+}
+
+// Every unknown key found by a Strict load, in the order they appear.
+type UnknownKeyErrors []*UnknownKeyError
+
+func (e UnknownKeyErrors) Error() string {
+	return joinErrors(e)
+}
+
+// Adds the unknown keys in err to found, returning err if it's any other kind of error. This lets
+// a load carry on looking for unknown keys in the rest of its files.
+func collectUnknownKeys(err error, found *UnknownKeyErrors) error {
+	if unknown, ok := err.(UnknownKeyErrors); ok {
+		*found = append(*found, unknown...)
+		return nil
+	}
+	return err
+}
+
+// Lists the paths of the keys in value that aren't in known, the defaults for the same object.
+// The keys of an unknown member aren't listed as well, and an empty object in known accepts any
+// keys. Arrays aren't checked.
+func unknownKeys(value interface{}, known interface{}, path string, found *[]string) {
+	object, ok := value.(map[string]interface{})
+	knownObject, knownOk := known.(map[string]interface{})
+	if !ok || !knownOk || len(knownObject) == 0 {
+		return
+	}
+
+	for _, key := range sortedMapKeys(object) {
+		if key == includeKey {
+			continue
+		}
+		knownMember, exists := knownObject[key]
+		if !exists {
+			*found = append(*found, joinPath(path, key))
+			continue
+		}
+		unknownKeys(object[key], knownMember, joinPath(path, key), found)
+	}
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// Lists the paths of the keys in value that encoding/json would ignore when decoding into a
+// valueType, as they don't match any field. Maps and interfaces accept any keys, as do types that
+// decode themselves.
+func unknownFields(value interface{}, valueType reflect.Type, path string, found *[]string) {
+	if valueType == nil {
+		return
+	}
+	for valueType.Kind() == reflect.Pointer {
+		valueType = valueType.Elem()
+	}
+	pointerType := reflect.PointerTo(valueType)
+	if pointerType.Implements(jsonUnmarshalerType) || pointerType.Implements(textUnmarshalerType) {
+		return
+	}
+
+	switch valueType.Kind() {
+	case reflect.Struct:
+		object, _ := value.(map[string]interface{})
+		for _, key := range sortedMapKeys(object) {
+			fieldType, ok := fieldTypeByJSONName(valueType, key)
+			if !ok {
+				*found = append(*found, joinPath(path, key))
+				continue
+			}
+			unknownFields(object[key], fieldType, joinPath(path, key), found)
+		}
+	case reflect.Map:
+		object, _ := value.(map[string]interface{})
+		for _, key := range sortedMapKeys(object) {
+			unknownFields(object[key], valueType.Elem(), joinPath(path, key), found)
+		}
+	case reflect.Slice, reflect.Array:
+		array, _ := value.([]interface{})
+		for i, element := range array {
+			unknownFields(element, valueType.Elem(), joinPath(path, strconv.Itoa(i)), found)
+		}
+	}
+}
+
+// Finds the type of the field of a struct type that encoding/json would decode the key into, in
+// the same way as fieldByJSONName.
+func fieldTypeByJSONName(structType reflect.Type, key string) (reflect.Type, bool) {
+	if fieldType, ok := findJSONFieldType(structType, key, false); ok {
+		return fieldType, true
+	}
+	return findJSONFieldType(structType, key, true)
+}
+
+// Does the work for fieldTypeByJSONName, matching either exactly or ignoring case.
+func findJSONFieldType(structType reflect.Type, key string, ignoreCase bool) (reflect.Type, bool) {
+	for i := 0; i < structType.NumField(); i++ {
+		fieldInfo := structType.Field(i)
+
+		if isPromoted(fieldInfo) {
+			embedded := fieldInfo.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if found, ok := findJSONFieldType(embedded, key, ignoreCase); ok {
+				return found, true
+			}
+			continue
+		}
+
+		name, ok := jsonFieldName(fieldInfo)
+		if !ok || !fieldInfo.IsExported() {
+			continue
+		}
+		if name == key || (ignoreCase && strings.EqualFold(name, key)) {
+			return fieldInfo.Type, true
+		}
+	}
+	return nil, false
+}
+
+// Turns the paths of unknown keys in source into UnknownKeyErrors, ordered by where they appear.
+// prefix is added to each path, for files included part way into a configuration.
+func locateUnknownKeys(filename string, source []byte, options *loadOptions, prefix string, paths []string) UnknownKeyErrors {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	offsets := keyOffsets(source, options)
+	sort.SliceStable(paths, func(i, j int) bool { return offsets[paths[i]] < offsets[paths[j]] })
+
+	errs := make(UnknownKeyErrors, len(paths))
+	for i, path := range paths {
+		location := newParseError(filename, source, offsets[path], nil)
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+		errs[i] = &UnknownKeyError{Path: path, Filename: filename, Line: location.Line, Column: location.Column}
+	}
+	return errs
+}
+
+// Finds the offset in source of every object key, by its dotted path.
+func keyOffsets(source []byte, options *loadOptions) map[string]int64 {
+	offsets := map[string]int64{}
+	if options.json5 {
+		parser := json5Parser{data: source, hashComments: options.hashComments(), keys: offsets}
+		if parser.skipWhitespace() == nil {
+			parser.parseValue()
+		}
+		return offsets
+	}
+
+	processor := preProcess(bytes.NewReader(source), options)
+	processed, err := io.ReadAll(processor)
+	if err != nil && len(processed) == 0 {
+		return offsets
+	}
+
+	// The containers that the decoder is currently inside
+	type container struct {
+		path      string
+		object    bool
+		expectKey bool
+		key       string
+		index     int
+	}
+	var stack []*container
+
+	decoder := json.NewDecoder(bytes.NewReader(processed))
+	for {
+		before := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err != nil {
+			return offsets
+		}
+
+		var top *container
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+
+		if delimiter, ok := token.(json.Delim); ok && (delimiter == '}' || delimiter == ']') {
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return offsets
+			}
+			top = stack[len(stack)-1]
+		} else if top != nil && top.object && top.expectKey {
+			// The separators before the key are included in the offset the decoder reports
+			start := before
+			for start < int64(len(processed)) && strings.IndexByte(" \t\r\n,", processed[start]) >= 0 {
+				start++
+			}
+			top.key, _ = token.(string)
+			top.expectKey = false
+			offsets[joinPath(top.path, top.key)] = processor.OriginalOffset(start)
+			continue
+		} else if delimiter, ok := token.(json.Delim); ok {
+			path := ""
+			if top != nil && top.object {
+				path = joinPath(top.path, top.key)
+			} else if top != nil {
+				path = joinPath(top.path, strconv.Itoa(top.index))
+			}
+			stack = append(stack, &container{path: path, object: delimiter == '{', expectKey: true})
+			continue
+		}
+
+		// A value has been completed within top
+		if top == nil {
+			return offsets
+		}
+		if top.object {
+			top.expectKey = true
+		} else {
+			top.index++
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package jsonconfig_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/callum-ramage/jsonconfig"
+)
+
+const strictDefaults = `{
+	"example_string": "",
+	"example_array": [],
+	"example_object": {"example_number": 0},
+	"labels": {},
+	"logging": {"level": "warn"}
+}`
+
+func TestStrictLoadAbstract(test *testing.T) {
+	_, err := jsonconfig.LoadAbstract("./configs/strict/Typo.conf", strictDefaults, jsonconfig.Strict())
+
+	var unknown jsonconfig.UnknownKeyErrors
+	if !errors.As(err, &unknown) {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	expected := []string{
+		`./configs/strict/Typo.conf:3:3: unknown key "exmaple_string"`,
+		`./configs/strict/Typo.conf:9:5: unknown key "example_object.example_bool"`,
+		`configs/strict/Logging.conf:4:5: unknown key "logging.fromat"`,
+	}
+	if len(unknown) != len(expected) {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+	for i, key := range unknown {
+		if key.Error() != expected[i] {
+			fmt.Println(key)
+			test.Error()
+		}
+	}
+}
+
+func TestStrictLoadAbstractAcceptsKnownKeys(test *testing.T) {
+	config, err := jsonconfig.LoadAbstract("./configs/strict/Typo.conf", strictDefaults)
+	if err != nil || config["exmaple_string"].Str != "string value" {
+		fmt.Println(err)
+		test.Error()
+	}
+
+	_, err = jsonconfig.LoadAbstract("./configs/ExampleConfig.conf", `{
+		"example_string": "",
+		"example_array": [],
+		"example_object": {"example_number": 0}
+	}`, jsonconfig.Strict())
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+	}
+}
+
+func TestStrictLoadString(test *testing.T) {
+	_, err := jsonconfig.LoadString("{\n  \"a\": 1,\n  \"b\": {\"c\": 2}\n}", `{"a": 0, "b": {"d": 0}}`, jsonconfig.Strict())
+	if err == nil || err.Error() != `jsonconfig: line 3, column 9: unknown key "b.c"` {
+		fmt.Println(err)
+		test.Error()
+	}
+}
+
+func TestStrictJSON5(test *testing.T) {
+	_, err := jsonconfig.LoadAbstract("./configs/strict/JSON5Typo.conf", `{"example_string": "", "example_object": {"example_number": 0}}`,
+		jsonconfig.Strict(), jsonconfig.JSON5())
+	if err == nil || err.Error() != `./configs/strict/JSON5Typo.conf:4:5: unknown key "example_object.example_numbr"` {
+		fmt.Println(err)
+		test.Error()
+	}
+}
+
+func TestStrictLoad(test *testing.T) {
+	type element struct {
+		Name string `json:"name"`
+	}
+	type embedded struct {
+		Labels map[string]string `json:"labels"`
+	}
+	var config struct {
+		embedded
+		ExampleString string    `json:"example_string"`
+		ExampleArray  []element `json:"example_array"`
+		ExampleObject struct {
+			ExampleNumber float64 `json:"example_number"`
+		} `json:"example_object"`
+		Include interface{} `json:"$include"`
+	}
+
+	err := jsonconfig.Load("./configs/strict/Typo.conf", &config, jsonconfig.Strict())
+
+	var unknown jsonconfig.UnknownKeyErrors
+	if !errors.As(err, &unknown) {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	expected := []string{"exmaple_string", "example_array.0.colour", "example_object.example_bool"}
+	if len(unknown) != len(expected) {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+	for i, key := range unknown {
+		if key.Path != expected[i] || key.Filename != "./configs/strict/Typo.conf" {
+			fmt.Println(key)
+			test.Error()
+		}
+	}
+	if unknown[1].Line != 5 || unknown[1].Column != 19 {
+		fmt.Println(unknown[1])
+		test.Error()
+	}
+
+	// Without Strict the unknown keys are ignored as usual
+	if err = jsonconfig.Load("./configs/strict/Typo.conf", &config); err != nil {
+		fmt.Println(err)
+		test.Error()
+	}
+}
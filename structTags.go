@@ -0,0 +1,350 @@
+package jsonconfig
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// The rules given to a struct field with a jsonconfig tag, such as
+//
+//	Port int `json:"port" jsonconfig:"required,min=1,max=65535"`
+type fieldRules struct {
+	required bool
+	min      string
+	max      string
+	oneOf    []string
+	// Whether the tag gave a default, which may be empty
+	hasDefault  bool
+	defaultText string
+}
+
+// Reads the rules from a jsonconfig tag. As a default may contain commas it must come last, and
+// everything after "default=" is taken as its text.
+func parseFieldRules(tag string) (fieldRules, error) {
+	var rules fieldRules
+	for tag != "" {
+		var rule string
+		if strings.HasPrefix(tag, "default=") {
+			rule, tag = tag, ""
+		} else {
+			rule, tag, _ = strings.Cut(tag, ",")
+		}
+
+		name, argument, hasArgument := strings.Cut(rule, "=")
+		switch {
+		case name == "required" && !hasArgument:
+			rules.required = true
+		case name == "min" && argument != "":
+			rules.min = argument
+		case name == "max" && argument != "":
+			rules.max = argument
+		case name == "oneof" && argument != "":
+			rules.oneOf = strings.Fields(argument)
+		case name == "default" && hasArgument:
+			rules.hasDefault = true
+			rules.defaultText = argument
+		case rule == "":
+		default:
+			return rules, fmt.Errorf("unknown rule %q", rule)
+		}
+	}
+	return rules, nil
+}
+
+// Reads the rules for a struct field from its jsonconfig tag and its default tag.
+func fieldRulesOf(field reflect.StructField) (fieldRules, error) {
+	rules, err := parseFieldRules(field.Tag.Get("jsonconfig"))
+	if err != nil {
+		return rules, err
+	}
+	if defaultText, ok := field.Tag.Lookup("default"); ok {
+		if rules.hasDefault {
+			return rules, fmt.Errorf("a default is given in both the jsonconfig and default tags")
+		}
+		rules.hasDefault = true
+		rules.defaultText = defaultText
+	}
+	return rules, nil
+}
+
+// Reports whether any field reachable from the type has a jsonconfig or default tag, which is the
+// only case in which Load needs to know which fields were actually in the file.
+func hasFieldRules(valueType reflect.Type, seen map[reflect.Type]bool) bool {
+	// A nil target has no type, and decoding into it fails later with the usual error
+	if valueType == nil {
+		return false
+	}
+	for valueType.Kind() == reflect.Pointer || valueType.Kind() == reflect.Slice || valueType.Kind() == reflect.Array || valueType.Kind() == reflect.Map {
+		valueType = valueType.Elem()
+	}
+	if valueType.Kind() != reflect.Struct || seen[valueType] {
+		return false
+	}
+	seen[valueType] = true
+
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if _, ok := field.Tag.Lookup("jsonconfig"); ok {
+			return true
+		}
+		if _, ok := field.Tag.Lookup("default"); ok || hasFieldRules(field.Type, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// Called for every struct field visited by walkFields, with the abstract value the field was
+// decoded from and whether it was in the file at all.
+type fieldVisitor func(field reflect.Value, rules fieldRules, member interface{}, present bool, path string) error
+
+// Visits every field of every struct reachable from value, along with the matching part of the
+// abstract tree it was decoded from. Each field is visited before anything inside it, so a
+// visitor that fills in a field gets to see what's inside it too. A field missing from the tree
+// but given a default is walked as if the default had been in the tree. Nil pointers are skipped.
+func walkFields(value reflect.Value, tree interface{}, path string, visit fieldVisitor) error {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		if value.CanAddr() && value.Addr().Type().Implements(textUnmarshalerType) {
+			return nil
+		}
+		object, _ := tree.(map[string]interface{})
+		for i := 0; i < value.NumField(); i++ {
+			fieldInfo := value.Type().Field(i)
+			if isPromoted(fieldInfo) {
+				if err := walkFields(value.Field(i), object, path, visit); err != nil {
+					return err
+				}
+				continue
+			}
+
+			name, ok := jsonFieldName(fieldInfo)
+			if !ok || !fieldInfo.IsExported() {
+				continue
+			}
+			rules, err := fieldRulesOf(fieldInfo)
+			if err != nil {
+				return fmt.Errorf("jsonconfig: invalid tag on %s.%s: %v", value.Type(), fieldInfo.Name, err)
+			}
+
+			fieldPath := joinPath(path, name)
+			member, present := memberForField(object, name)
+			if err = visit(value.Field(i), rules, member, present, fieldPath); err != nil {
+				return err
+			}
+			// A field that was filled from its default is walked with the default in place of the
+			// file, so what the default sets counts as present
+			if !present && rules.hasDefault {
+				member, _ = parseJSONText(rules.defaultText, nil)
+			}
+			if err = walkFields(value.Field(i), member, fieldPath, visit); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		array, _ := tree.([]interface{})
+		for i := 0; i < value.Len(); i++ {
+			var element interface{}
+			if i < len(array) {
+				element = array[i]
+			}
+			if err := walkFields(value.Index(i), element, joinPath(path, strconv.Itoa(i)), visit); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		object, _ := tree.(map[string]interface{})
+		for _, key := range sortedMapKeys(stringKeys(value)) {
+			err := updateMapEntry(value, key, func(entry reflect.Value) error {
+				return walkFields(entry, object[key], joinPath(path, key), visit)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Collects the keys of a map with string keys, so they can be visited in order.
+func stringKeys(value reflect.Value) map[string]interface{} {
+	keys := make(map[string]interface{}, value.Len())
+	for _, key := range value.MapKeys() {
+		keys[key.String()] = nil
+	}
+	return keys
+}
+
+// Finds the member of an abstract object that encoding/json would decode into the field with the
+// name, preferring an exact match over a case insensitive one.
+func memberForField(object map[string]interface{}, name string) (interface{}, bool) {
+	if member, ok := object[name]; ok {
+		return member, true
+	}
+	for key, member := range object {
+		if strings.EqualFold(key, name) {
+			return member, true
+		}
+	}
+	return nil, false
+}
+
+// Fills in fields that weren't in the file from their default tag or default= rule. Defaults are
+// applied from the outside in, so a struct, pointer or slice filled in from its own default
+// still has the defaults of its fields applied.
+func applyTagDefaults(target interface{}, tree interface{}) error {
+	return walkFields(reflect.ValueOf(target), tree, "", func(field reflect.Value, rules fieldRules, member interface{}, present bool, path string) error {
+		if present || !rules.hasDefault {
+			return nil
+		}
+		for field.Kind() == reflect.Pointer {
+			if field.IsNil() {
+				field.Set(reflect.New(field.Type().Elem()))
+			}
+			field = field.Elem()
+		}
+		if err := setFromText(field, rules.defaultText); err != nil {
+			return fmt.Errorf("jsonconfig: invalid default for %s: %v", path, err)
+		}
+		return nil
+	})
+}
+
+// Checks every field against the rules in its jsonconfig tag, returning all of the failures as
+// ValidationErrors.
+func checkFieldRules(target interface{}, tree interface{}) error {
+	var errs ValidationErrors
+	err := walkFields(reflect.ValueOf(target), tree, "", func(field reflect.Value, rules fieldRules, member interface{}, present bool, path string) error {
+		if !present && !rules.hasDefault {
+			if rules.required {
+				errs.add(path, "required", "is required")
+				return nil
+			}
+			// Nothing has been put in the field, so there's nothing to check
+			if field.IsZero() {
+				return nil
+			}
+		}
+		return checkField(field, rules, path, &errs)
+	})
+	if err != nil {
+		return err
+	}
+	return errorsOrNil(errs)
+}
+
+// Checks the value of a single field against its min, max and oneof rules. Numbers are compared
+// by value, strings by their length in characters and slices and maps by their number of elements.
+func checkField(field reflect.Value, rules fieldRules, path string, errs *ValidationErrors) error {
+	for field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			return nil
+		}
+		field = field.Elem()
+	}
+
+	if rules.min != "" || rules.max != "" {
+		measure, unit, err := measureField(field)
+		if err != nil {
+			return fmt.Errorf("jsonconfig: invalid tag for %s: %v", path, err)
+		}
+		for _, bound := range []struct{ keyword, text, comparison string }{{"min", rules.min, "at least"}, {"max", rules.max, "at most"}} {
+			if bound.text == "" {
+				continue
+			}
+			limit, err := parseBound(field.Type(), bound.text)
+			if err != nil {
+				return fmt.Errorf("jsonconfig: invalid %s rule for %s: %v", bound.keyword, path, err)
+			}
+			if (bound.keyword == "min" && measure < limit) || (bound.keyword == "max" && measure > limit) {
+				switch unit {
+				case "characters":
+					errs.add(path, bound.keyword, "must be %s %s characters long", bound.comparison, bound.text)
+				case "elements":
+					errs.add(path, bound.keyword, "must have %s %s elements", bound.comparison, bound.text)
+				default:
+					errs.add(path, bound.keyword, "must be %s %s", bound.comparison, bound.text)
+				}
+			}
+		}
+	}
+
+	if len(rules.oneOf) > 0 {
+		text, ok := fieldText(field)
+		if !ok {
+			return fmt.Errorf("jsonconfig: invalid tag for %s: oneof can't be used with a %s", path, field.Type())
+		}
+		for _, allowed := range rules.oneOf {
+			if text == allowed {
+				return nil
+			}
+		}
+		errs.add(path, "oneof", "must be one of %s", strings.Join(rules.oneOf, ", "))
+	}
+	return nil
+}
+
+// Returns the quantity that min and max compare for the field, along with what it's measured in.
+func measureField(field reflect.Value) (float64, string, error) {
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(field.Int()), "", nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(field.Uint()), "", nil
+	case reflect.Float32, reflect.Float64:
+		return field.Float(), "", nil
+	case reflect.String:
+		return float64(utf8.RuneCountInString(field.String())), "characters", nil
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(field.Len()), "elements", nil
+	default:
+		return 0, "", fmt.Errorf("min and max can't be used with a %s", field.Type())
+	}
+}
+
+// Parses the argument of a min or max rule. A time.Duration may be bounded with a duration such
+// as "1s".
+func parseBound(fieldType reflect.Type, text string) (float64, error) {
+	if fieldType == durationType {
+		if duration, err := time.ParseDuration(text); err == nil {
+			return float64(duration), nil
+		}
+	}
+	bound, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", text)
+	}
+	return bound, nil
+}
+
+// Writes a simple value as text for comparing with the values in a oneof rule.
+func fieldText(field reflect.Value) (string, bool) {
+	switch field.Kind() {
+	case reflect.String:
+		return field.String(), true
+	case reflect.Bool:
+		return strconv.FormatBool(field.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(field.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(field.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(field.Float(), 'g', -1, field.Type().Bits()), true
+	default:
+		return "", false
+	}
+}
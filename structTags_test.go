@@ -0,0 +1,217 @@
+package jsonconfig_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/callum-ramage/jsonconfig"
+)
+
+type taggedUpstream struct {
+	Host   string  `json:"host" jsonconfig:"required"`
+	Weight float64 `json:"weight" jsonconfig:"min=0.1,max=10"`
+}
+
+type taggedConfiguration struct {
+	Name   string `json:"name" jsonconfig:"required,min=1,max=20"`
+	Server struct {
+		Host    string        `json:"host" jsonconfig:"required"`
+		Port    int           `json:"port" jsonconfig:"min=1,max=65535,default=8080"`
+		Retries int           `json:"retries" jsonconfig:"required"`
+		Timeout time.Duration `json:"timeout" jsonconfig:"max=1m,default=30s"`
+	} `json:"server"`
+	Level     string            `json:"level" jsonconfig:"oneof=debug info warn error,default=info"`
+	Upstreams []taggedUpstream  `json:"upstreams" jsonconfig:"min=1"`
+	Tags      map[string]string `json:"tags" jsonconfig:"max=3"`
+	Greeting  string            `json:"greeting" jsonconfig:"default=hello, world"`
+}
+
+func TestLoadTags(test *testing.T) {
+	var config taggedConfiguration
+	if err := jsonconfig.Load("./configs/tags/Valid.conf", &config); err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	if config.Server.Port != 8080 || config.Server.Timeout != 30*time.Second || config.Level != "warn" || config.Greeting != "hello, world" {
+		fmt.Println(config)
+		test.Error()
+	}
+}
+
+func TestLoadTagsReportsEveryFailure(test *testing.T) {
+	var config taggedConfiguration
+	err := jsonconfig.Load("./configs/tags/Invalid.conf", &config)
+
+	var failures jsonconfig.ValidationErrors
+	if !errors.As(err, &failures) {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	expected := []string{
+		"name: must be at least 1 characters long",
+		"server.host: is required",
+		"server.port: must be at most 65535",
+		"level: must be one of debug, info, warn, error",
+		"upstreams.0.weight: must be at most 10",
+		"upstreams.1.host: is required",
+	}
+	if len(failures) != len(expected) {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+	for i, failure := range failures {
+		if failure.Error() != "jsonconfig: "+expected[i] {
+			fmt.Println(failure)
+			test.Error()
+		}
+	}
+}
+
+func TestLoadTagsChecksPrepopulatedValues(test *testing.T) {
+	config := struct {
+		Missing string `json:"missing" jsonconfig:"oneof=a b"`
+		Preset  string `json:"preset" jsonconfig:"oneof=a b"`
+	}{Preset: "c"}
+
+	err := jsonconfig.Load("./configs/tags/Valid.conf", &config)
+	if failures, ok := err.(jsonconfig.ValidationErrors); !ok || len(failures) != 1 || failures[0].Path != "preset" {
+		fmt.Println(err)
+		test.Error()
+	}
+}
+
+func TestLoadInvalidTags(test *testing.T) {
+	var badRule struct {
+		Name string `json:"name" jsonconfig:"requird"`
+	}
+	if err := jsonconfig.Load("./configs/tags/Valid.conf", &badRule); err == nil {
+		test.Error()
+	}
+
+	var badBound struct {
+		Name string `json:"name" jsonconfig:"min=one"`
+	}
+	if err := jsonconfig.Load("./configs/tags/Valid.conf", &badBound); err == nil {
+		test.Error()
+	}
+
+	var badDefault struct {
+		Count int `json:"count" jsonconfig:"default=many"`
+	}
+	if err := jsonconfig.Load("./configs/tags/Valid.conf", &badDefault); err == nil {
+		test.Error()
+	}
+}
+
+type defaultedTLS struct {
+	Certificate string `json:"certificate"`
+	MinVersion  string `json:"min_version" default:"1.2"`
+}
+
+type defaultedUpstream struct {
+	Host   string  `json:"host"`
+	Weight float64 `json:"weight" default:"1"`
+}
+
+type defaultedConfiguration struct {
+	Name    string        `json:"name" default:"service"`
+	Retries int           `json:"retries" default:"3"`
+	Timeout time.Duration `json:"timeout" default:"5s"`
+	Proxy   *string       `json:"proxy" default:"http://proxy"`
+	Server  struct {
+		Host string `json:"host" default:"localhost"`
+		Port int    `json:"port" default:"8080"`
+	} `json:"server"`
+	Metrics struct {
+		Enabled bool `json:"enabled" default:"true"`
+	} `json:"metrics"`
+	TLS       *defaultedTLS                `json:"tls"`
+	Auth      *defaultedTLS                `json:"auth"`
+	Cache     *defaultedTLS                `json:"cache" default:"{}"`
+	Upstreams []defaultedUpstream          `json:"upstreams"`
+	Fallbacks []defaultedUpstream          `json:"fallbacks" default:"[{\"host\": \"c.internal\"}]"`
+	Routes    map[string]defaultedUpstream `json:"routes"`
+}
+
+func TestLoadDefaultTags(test *testing.T) {
+	var config defaultedConfiguration
+	if err := jsonconfig.Load("./configs/tags/Defaults.conf", &config); err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	// Values that are in the file, even as zero values, are kept
+	if config.Name != "" || config.Retries != 0 || config.Proxy != nil || config.Server.Port != 9000 {
+		fmt.Println(config)
+		test.Error()
+	}
+
+	if config.Timeout != 5*time.Second || config.Server.Host != "localhost" || !config.Metrics.Enabled {
+		fmt.Println(config)
+		test.Error()
+	}
+
+	if config.TLS == nil || config.TLS.Certificate != "/etc/tls/cert.pem" || config.TLS.MinVersion != "1.2" {
+		fmt.Println(config.TLS)
+		test.Error()
+	}
+	if config.Auth != nil {
+		fmt.Println(config.Auth)
+		test.Error()
+	}
+	if config.Cache == nil || config.Cache.MinVersion != "1.2" {
+		fmt.Println(config.Cache)
+		test.Error()
+	}
+
+	if len(config.Upstreams) != 2 || config.Upstreams[0].Weight != 1 || config.Upstreams[1].Weight != 0 {
+		fmt.Println(config.Upstreams)
+		test.Error()
+	}
+	if len(config.Fallbacks) != 1 || config.Fallbacks[0].Host != "c.internal" || config.Fallbacks[0].Weight != 1 {
+		fmt.Println(config.Fallbacks)
+		test.Error()
+	}
+	if config.Routes["api"].Weight != 1 {
+		fmt.Println(config.Routes)
+		test.Error()
+	}
+}
+
+func TestLoadDefaultTagsWithinDefaults(test *testing.T) {
+	type upstream struct {
+		Host string `json:"host" default:"localhost"`
+		Port int    `json:"port" jsonconfig:"required"`
+	}
+	var config struct {
+		Pool []upstream `json:"pool" default:"[{\"host\": \"a.internal\", \"port\": 9}, {\"port\": 10}]"`
+	}
+	if err := jsonconfig.Load("./configs/tags/Defaults.conf", &config); err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+
+	// What the default sets is kept, and the tags of the elements fill in the rest
+	if len(config.Pool) != 2 || config.Pool[0] != (upstream{Host: "a.internal", Port: 9}) || config.Pool[1] != (upstream{Host: "localhost", Port: 10}) {
+		fmt.Println(config.Pool)
+		test.Error()
+	}
+}
+
+func TestLoadConflictingDefaults(test *testing.T) {
+	var config struct {
+		Name string `json:"name" jsonconfig:"default=a" default:"b"`
+	}
+	if err := jsonconfig.Load("./configs/tags/Defaults.conf", &config); err == nil {
+		test.Error()
+	}
+}
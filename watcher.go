@@ -0,0 +1,226 @@
+package jsonconfig
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// The size and modification time of a watched file, or missing if it couldn't be found.
+type fileState struct {
+	size    int64
+	modTime time.Time
+	missing bool
+}
+
+// Returns the current state of the file.
+func statFile(filename string) fileState {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return fileState{missing: true}
+	}
+	return fileState{size: info.Size(), modTime: info.ModTime()}
+}
+
+// Watches a configuration file, and every file it pulls in with $include, reloading it with
+// LoadAbstract whenever one of them changes. The files are polled, which works the same on
+// every platform and filesystem, including network mounts where change notifications don't.
+//
+// A reload that fails leaves the last good configuration in place, so a half written file never
+// takes down a running service. The failure is available from Err and is passed to any OnError
+// callbacks. The configuration is kept in a Holder, so it can be read from any goroutine while a
+// reload is going on, and a Configuration handed out is never altered by the Watcher.
+//
+//	watcher, err := jsonconfig.NewWatcher("./configs/ExampleConfig.conf", "", time.Second)
+//	if err != nil {
+//	  return
+//	}
+//	defer watcher.Close()
+//
+//	watcher.OnChange(func(old, new jsonconfig.Configuration) {
+//	  fmt.Println("example_string is now", new["example_string"].Str)
+//	})
+type Watcher struct {
+	filename string
+	defaults string
+	options  *loadOptions
+
+	holder *Holder
+
+	mutex          sync.Mutex
+	err            error
+	files          map[string]fileState
+	changeHandlers []func(old Configuration, new Configuration)
+	errorHandlers  []func(err error)
+
+	// Serialises reloads so that callbacks see the configurations in order
+	reloading sync.Mutex
+	stop      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+// Loads the file in the same way as LoadAbstract and starts checking it for changes every
+// interval, which must be positive. An error is returned if the first load fails.
+func NewWatcher(filename string, defaults string, interval time.Duration, options ...LoadOption) (*Watcher, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("jsonconfig: a Watcher needs a positive interval, not %v", interval)
+	}
+
+	watcher := &Watcher{
+		filename: filename,
+		defaults: defaults,
+		options:  newLoadOptions(options),
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+
+	config, files, err := watcher.load()
+	if err != nil {
+		return nil, err
+	}
+	watcher.holder = NewHolder(config)
+	watcher.files = files
+
+	go watcher.poll(interval)
+	return watcher, nil
+}
+
+// Loads the configuration, noting the state of every file that went into it.
+func (w *Watcher) load() (Configuration, map[string]fileState, error) {
+	var filenames []string
+	config, err := loadAbstract(w.filename, w.defaults, w.options, &filenames)
+
+	files := map[string]fileState{}
+	for _, filename := range filenames {
+		files[filename] = statFile(filename)
+	}
+	// The main file is watched even when it couldn't be read, so that fixing it is noticed
+	if len(filenames) == 0 {
+		files[w.filename] = statFile(w.filename)
+	}
+
+	if err != nil {
+		return nil, files, err
+	}
+	config.Collapse()
+	return config, files, nil
+}
+
+// Checks the files every interval until the Watcher is closed.
+func (w *Watcher) poll(interval time.Duration) {
+	defer close(w.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			if w.changed() {
+				w.Reload()
+			}
+		}
+	}
+}
+
+// Reports whether any of the watched files have changed since they were last loaded.
+func (w *Watcher) changed() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for filename, state := range w.files {
+		if statFile(filename) != state {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the most recently loaded configuration.
+func (w *Watcher) Config() Configuration {
+	return w.holder.Config()
+}
+
+// Returns the holder of the configuration, whose version goes up with each reload that changes it.
+func (w *Watcher) Holder() *Holder {
+	return w.holder
+}
+
+// Returns the error from the most recent reload, or nil if it succeeded.
+func (w *Watcher) Err() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.err
+}
+
+// Registers a func to be called with the old and new configurations after each reload that
+// changes the configuration. Callbacks are called one at a time from the Watcher's goroutine, so
+// a slow callback delays the next reload.
+func (w *Watcher) OnChange(callback func(old Configuration, new Configuration)) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.changeHandlers = append(w.changeHandlers, callback)
+}
+
+// Registers a func to be called with the error each time a reload fails.
+func (w *Watcher) OnError(callback func(err error)) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.errorHandlers = append(w.errorHandlers, callback)
+}
+
+// Loads the configuration again straight away, without waiting for a file to change. Callbacks
+// are called before it returns. On failure the last good configuration is kept and the error
+// is returned.
+func (w *Watcher) Reload() error {
+	w.reloading.Lock()
+	defer w.reloading.Unlock()
+
+	config, files, err := w.load()
+
+	w.mutex.Lock()
+	// A failed load may not have reached every file, such as an include that was deleted, so the
+	// files from before are still watched in case they come back
+	if err != nil {
+		for filename := range w.files {
+			if _, ok := files[filename]; !ok {
+				files[filename] = statFile(filename)
+			}
+		}
+	}
+	w.files = files
+	w.err = err
+	changeHandlers := w.changeHandlers
+	errorHandlers := w.errorHandlers
+	w.mutex.Unlock()
+
+	if err != nil {
+		for _, handler := range errorHandlers {
+			handler(err)
+		}
+		return err
+	}
+
+	old := w.holder.Config()
+	if !reflect.DeepEqual(old.raw(), config.raw()) {
+		w.holder.Replace(config)
+		for _, handler := range changeHandlers {
+			handler(old, config)
+		}
+	}
+	return nil
+}
+
+// Stops watching the files. Config can still be called afterwards.
+func (w *Watcher) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.stop)
+	})
+	<-w.stopped
+	return nil
+}
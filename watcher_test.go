@@ -0,0 +1,206 @@
+package jsonconfig_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/callum-ramage/jsonconfig"
+)
+
+// Writes content to the file and pushes its modification time forward, so the change is seen
+// even on filesystems with coarse timestamps.
+func rewriteFile(test *testing.T, filename string, content string, age int) {
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		test.Fatal(err)
+	}
+	modTime := time.Now().Add(time.Duration(age) * time.Second)
+	if err := os.Chtimes(filename, modTime, modTime); err != nil {
+		test.Fatal(err)
+	}
+}
+
+func TestWatcherReloads(test *testing.T) {
+	directory := test.TempDir()
+	filename := filepath.Join(directory, "Watched.conf")
+	rewriteFile(test, filename, `{"server": {"port": 80}}`, 1)
+
+	watcher, err := jsonconfig.NewWatcher(filename, `{"debug": false}`, 10*time.Millisecond)
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+	defer watcher.Close()
+
+	if watcher.Config()["server.port"].Value != float64(80) || watcher.Config()["debug"].Value != false {
+		fmt.Println(watcher.Config())
+		test.Error()
+	}
+
+	changes := make(chan [2]jsonconfig.Configuration, 1)
+	watcher.OnChange(func(old, new jsonconfig.Configuration) {
+		changes <- [2]jsonconfig.Configuration{old, new}
+	})
+
+	rewriteFile(test, filename, `{"server": {"port": 8080}}`, 2)
+
+	select {
+	case change := <-changes:
+		if change[0]["server.port"].Value != float64(80) || change[1]["server.port"].Value != float64(8080) {
+			fmt.Println(change[0], change[1])
+			test.Error()
+		}
+	case <-time.After(5 * time.Second):
+		fmt.Println("no change was reported")
+		test.Error()
+		return
+	}
+
+	if watcher.Config()["server.port"].Value != float64(8080) || watcher.Err() != nil {
+		fmt.Println(watcher.Config(), watcher.Err())
+		test.Error()
+	}
+}
+
+func TestWatcherKeepsLastGoodConfig(test *testing.T) {
+	directory := test.TempDir()
+	filename := filepath.Join(directory, "Watched.conf")
+	rewriteFile(test, filename, `{"name": "first"}`, 1)
+
+	watcher, err := jsonconfig.NewWatcher(filename, "", 10*time.Millisecond)
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+	defer watcher.Close()
+
+	errs := make(chan error, 1)
+	watcher.OnError(func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	})
+	watcher.OnChange(func(old, new jsonconfig.Configuration) {
+		fmt.Println("unexpected change", new)
+		test.Error()
+	})
+
+	rewriteFile(test, filename, `{"name": `, 2)
+
+	select {
+	case err := <-errs:
+		if _, ok := err.(*jsonconfig.ParseError); !ok {
+			fmt.Println(err)
+			test.Error()
+		}
+	case <-time.After(5 * time.Second):
+		fmt.Println("no error was reported")
+		test.Error()
+	}
+
+	if watcher.Config()["name"].Str != "first" || watcher.Err() == nil {
+		fmt.Println(watcher.Config(), watcher.Err())
+		test.Error()
+	}
+}
+
+func TestWatcherWatchesIncludes(test *testing.T) {
+	directory := test.TempDir()
+	filename := filepath.Join(directory, "Main.conf")
+	included := filepath.Join(directory, "Included.conf")
+	rewriteFile(test, included, `{"level": "info"}`, 1)
+	rewriteFile(test, filename, `{"$include": "Included.conf"}`, 1)
+
+	watcher, err := jsonconfig.NewWatcher(filename, "", time.Hour)
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+	defer watcher.Close()
+
+	changed := 0
+	watcher.OnChange(func(old, new jsonconfig.Configuration) {
+		changed++
+	})
+
+	// Reloading without changes doesn't report anything
+	if err := watcher.Reload(); err != nil || changed != 0 {
+		fmt.Println(err, changed)
+		test.Error()
+	}
+
+	rewriteFile(test, included, `{"level": "debug"}`, 2)
+	if err := watcher.Reload(); err != nil || changed != 1 {
+		fmt.Println(err, changed)
+		test.Error()
+	}
+
+	if watcher.Config()["level"].Str != "debug" {
+		fmt.Println(watcher.Config())
+		test.Error()
+	}
+}
+
+func TestWatcherNoticesRestoredInclude(test *testing.T) {
+	directory := test.TempDir()
+	filename := filepath.Join(directory, "Main.conf")
+	included := filepath.Join(directory, "Included.conf")
+	rewriteFile(test, included, `{"level": "info"}`, 1)
+	rewriteFile(test, filename, `{"$include": "Included.conf"}`, 1)
+
+	watcher, err := jsonconfig.NewWatcher(filename, "", 10*time.Millisecond)
+	if err != nil {
+		fmt.Println(err)
+		test.Error()
+		return
+	}
+	defer watcher.Close()
+
+	changes := make(chan jsonconfig.Configuration, 1)
+	watcher.OnChange(func(old, new jsonconfig.Configuration) {
+		changes <- new
+	})
+
+	if err := os.Remove(included); err != nil {
+		test.Fatal(err)
+	}
+	if err := watcher.Reload(); err == nil {
+		fmt.Println("deleting the include wasn't reported")
+		test.Error()
+	}
+
+	rewriteFile(test, included, `{"level": "debug"}`, 2)
+
+	select {
+	case config := <-changes:
+		if config["level"].Str != "debug" {
+			fmt.Println(config)
+			test.Error()
+		}
+	case <-time.After(5 * time.Second):
+		fmt.Println("restoring the include wasn't noticed")
+		test.Error()
+	}
+}
+
+func TestNewWatcherFailsOnBadConfig(test *testing.T) {
+	_, err := jsonconfig.NewWatcher("./configs/InvalidConfig.conf", "", time.Second)
+	if err == nil {
+		test.Error()
+	}
+}
+
+func TestNewWatcherRejectsBadInterval(test *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if _, err := jsonconfig.NewWatcher("./configs/ExampleConfig.conf", "", interval); err == nil {
+			fmt.Println(interval)
+			test.Error()
+		}
+	}
+}